@@ -4,6 +4,7 @@ echo "Formatting go files"
 go fmt src/*.go
 go fmt src/interpreter/*.go
 go fmt src/stdlib/*.go
+go fmt src/formats/*.go
 
 echo ""
 echo "Running unit tests."
@@ -16,6 +17,9 @@ go test
 cd ../codegen
 echo "  * Code Geenerators"
 go test
+cd ../formats
+echo "  * Output Formats"
+go test
 cd ..
 echo "  * Unicorn"
 go test
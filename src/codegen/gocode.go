@@ -1,6 +1,7 @@
 package codegen
 
 import (
+	uni "../interpreter"
 	"fmt"
 	"os"
 	"strings"
@@ -81,9 +82,8 @@ func createFields(env map[string]interface{}) []string {
 	index := 0
 	for k, v := range env {
 		field := strings.Replace(FieldTemplate, "{{.FieldName}}", fieldName(k), 1)
-		tags := fmt.Sprintf("`json:\"%s\",yaml:\"%s\"`", k, k)
-		field = strings.Replace(field, "{{.Tags}}", tags, 1)
 		typeName := ""
+		options := ""
 		switch v.(type) {
 		case string:
 			typeName = "string"
@@ -97,7 +97,15 @@ func createFields(env map[string]interface{}) []string {
 			typeName = "[]interface{}"
 		case map[string]interface{}:
 			typeName = "map[string]interface{}"
+		case uni.Nil:
+			// A nil value says nothing about the type the field would have, so it can hold anything
+			typeName = "interface{}"
+			options = ",omitempty"
+		case fmt.Stringer:
+			typeName = "string"
 		}
+		tags := fmt.Sprintf("`json:\"%s%s\",yaml:\"%s%s\"`", k, options, k, options)
+		field = strings.Replace(field, "{{.Tags}}", tags, 1)
 		field = strings.Replace(field, "{{.Type}}", typeName, 1)
 		fields[index] = field
 		index++
@@ -0,0 +1,176 @@
+package formats
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+/**
+ * Write configuration data to a dotenv file of KEY=value lines.
+ */
+func WriteDotenv(env map[string]interface{}, fileName string) error {
+	encoded, err := EncodeDotenv(env)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, encoded, os.ModePerm)
+}
+
+/**
+ * Write configuration data to a shell script of `export KEY=value` lines.
+ */
+func WriteShell(env map[string]interface{}, fileName string) error {
+	encoded, err := EncodeShell(env)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, encoded, os.ModePerm)
+}
+
+/**
+ * Encode configuration data as a dotenv file.  Values that are not made up only of characters that are safe
+ * everywhere are quoted, with single quotes when possible, since they are taken literally by dotenv parsers.
+ * This includes values containing $, which parsers such as godotenv and python-dotenv would otherwise expand.
+ * Only values containing single quotes or line breaks, which cannot be written in single quotes, are written in
+ * double quotes.
+ */
+func EncodeDotenv(env map[string]interface{}) ([]byte, error) {
+	vars, err := flattenEnv(env)
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	for _, v := range vars {
+		value := v.Value
+		if !safeEnvValue.MatchString(value) {
+			if strings.ContainsAny(value, "'\n") {
+				value = doubleQuoted(value)
+			} else {
+				value = "'" + value + "'"
+			}
+		}
+		fmt.Fprintf(&buffer, "%s=%s\n", v.Name, value)
+	}
+	return buffer.Bytes(), nil
+}
+
+/**
+ * Encode configuration data as a POSIX shell script that exports each value as an environment variable.
+ */
+func EncodeShell(env map[string]interface{}) ([]byte, error) {
+	vars, err := flattenEnv(env)
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	for _, v := range vars {
+		fmt.Fprintf(&buffer, "export %s=%s\n", v.Name, shellQuoted(v.Value))
+	}
+	return buffer.Bytes(), nil
+}
+
+// An environment variable flattened out of configuration data
+type envVar struct {
+	Name  string
+	Value string
+}
+
+// Valid environment variable names, as accepted by POSIX shells
+var envVarName = regexp.MustCompile("^[A-Z_][A-Z0-9_]*$")
+
+// Values made up of these characters mean the same thing with or without quotes
+var safeEnvValue = regexp.MustCompile("^[A-Za-z0-9_@%+=:,./-]*$")
+
+/**
+ * Flatten configuration data into environment variables sorted by name.  Nested maps are joined to the names
+ * of the maps containing them with underscores, as are the indices of elements in lists, so that
+ * {"db": {"hosts": ["a"]}} produces DB_HOSTS_0=a.  Nil produces an empty value.  The paths that produced each
+ * name are tracked so that two values flattening to the same name can be reported.
+ */
+func flattenEnv(env map[string]interface{}) ([]envVar, error) {
+	vars := []envVar{}
+	paths := map[string]string{}
+	err := flatten(env, nil, func(path []string, value interface{}) error {
+		words := make([]string, len(path))
+		for i, key := range path {
+			words[i] = envName(key)
+		}
+		name := strings.Join(words, "_")
+		if !envVarName.MatchString(name) {
+			errMsg := fmt.Sprintf("The key %s cannot be represented as an environment variable. It becomes %s.",
+				dottedPath(path), name)
+			return errors.New(errMsg)
+		}
+		if err := claimKey(paths, name, path, "the environment variable "+name); err != nil {
+			return err
+		}
+		str, ok := flatValue(value)
+		if !ok {
+			errMsg := fmt.Sprintf("The value %v of %s cannot be represented as an environment variable.",
+				value, dottedPath(path))
+			return errors.New(errMsg)
+		}
+		vars = append(vars, envVar{name, str})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(vars, func(i, j int) bool {
+		return vars[i].Name < vars[j].Name
+	})
+	return vars, nil
+}
+
+/**
+ * Convert a key to UPPER_SNAKE case.  Words in camelCase keys are separated, and ASCII characters other than
+ * letters and digits become underscores.  Other characters are kept, so that the name can be reported as invalid.
+ */
+func envName(key string) string {
+	var name bytes.Buffer
+	runes := []rune(key)
+	for i, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			name.WriteRune(r - 'a' + 'A')
+		case r >= 'A' && r <= 'Z':
+			if i > 0 && ((runes[i-1] >= 'a' && runes[i-1] <= 'z') || (runes[i-1] >= '0' && runes[i-1] <= '9')) {
+				name.WriteByte('_')
+			}
+			name.WriteRune(r)
+		case r >= '0' && r <= '9':
+			name.WriteRune(r)
+		case r < 0x80:
+			name.WriteByte('_')
+		default:
+			name.WriteRune(r)
+		}
+	}
+	return name.String()
+}
+
+/**
+ * Quote a value for a POSIX shell.  Single quotes take everything between them literally, so the only character
+ * needing special treatment is the single quote itself, which is closed, escaped and reopened.
+ */
+func shellQuoted(value string) string {
+	if value != "" && safeEnvValue.MatchString(value) {
+		return value
+	}
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+/**
+ * Quote a value in double quotes, escaping the characters that are special inside of them.  A $ is written as \$,
+ * which godotenv and Ruby's dotenv read as a literal dollar sign rather than the start of a variable to expand.
+ */
+func doubleQuoted(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "$", `\$`, "`", "\\`")
+	return `"` + replacer.Replace(value) + `"`
+}
@@ -0,0 +1,105 @@
+package formats
+
+import (
+	uni "../interpreter"
+	"strings"
+	"testing"
+)
+
+var envTestData = map[string]interface{}{
+	"firstWord": "fig",
+	"port":      int64(8080),
+	"ratio":     0.5,
+	"debug":     true,
+	"optional":  uni.Nil{},
+	"stage":     uni.Keyword{Contained: "prod"},
+	"database": map[string]interface{}{
+		"host-name": "db.internal",
+		"password":  "it's $ecret",
+		"replicas":  []interface{}{"a b", "c"},
+	},
+	"motd": "line one\nline two",
+}
+
+func TestEncodeDotenv(t *testing.T) {
+	expected := `DATABASE_HOST_NAME=db.internal
+DATABASE_PASSWORD="it's \$ecret"
+DATABASE_REPLICAS_0='a b'
+DATABASE_REPLICAS_1=c
+DEBUG=true
+FIRST_WORD=fig
+MOTD="line one\nline two"
+OPTIONAL=
+PORT=8080
+RATIO=0.5
+STAGE=prod
+`
+	encoded, err := EncodeDotenv(envTestData)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if string(encoded) != expected {
+		t.Errorf("Encoding dotenv produced\n%s\nExpected\n%s\n", encoded, expected)
+	}
+}
+
+func TestEncodeDotenvDollars(t *testing.T) {
+	// Values containing $ are single-quoted so that dotenv parsers do not expand them, unless they cannot be
+	tests := map[string]string{
+		"$HOME":       `VALUE='$HOME'`,
+		"a ${b} c":    `VALUE='a ${b} c'`,
+		"it's $x":     `VALUE="it's \$x"`,
+		"$a\nb":       `VALUE="\$a\nb"`,
+		"price: 5 $$": `VALUE='price: 5 $$'`,
+	}
+	for value, expected := range tests {
+		encoded, err := EncodeDotenv(map[string]interface{}{"value": value})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if strings.TrimSuffix(string(encoded), "\n") != expected {
+			t.Errorf("Expected %q to be encoded as %s. Got %s\n", value, expected, encoded)
+		}
+	}
+}
+
+func TestEncodeShell(t *testing.T) {
+	expected := `export DATABASE_HOST_NAME=db.internal
+export DATABASE_PASSWORD='it'\''s $ecret'
+export DATABASE_REPLICAS_0='a b'
+export DATABASE_REPLICAS_1=c
+export DEBUG=true
+export FIRST_WORD=fig
+export MOTD='line one
+line two'
+export OPTIONAL=''
+export PORT=8080
+export RATIO=0.5
+export STAGE=prod
+`
+	encoded, err := EncodeShell(envTestData)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if string(encoded) != expected {
+		t.Errorf("Encoding shell exports produced\n%s\nExpected\n%s\n", encoded, expected)
+	}
+}
+
+func TestEncodeEnvErrors(t *testing.T) {
+	tests := []struct {
+		Env   map[string]interface{}
+		Error string
+	}{
+		{map[string]interface{}{"größe": int64(1)}, "The key größe cannot be represented as an environment variable."},
+		{map[string]interface{}{"1st": int64(1)}, "The key 1st cannot be represented as an environment variable."},
+		{map[string]interface{}{"a": map[string]interface{}{"b-c": int64(1)}, "a_b": map[string]interface{}{"c": int64(2)}},
+			"The keys a.b-c and a_b.c both become the environment variable A_B_C."},
+	}
+	for _, test := range tests {
+		_, err := EncodeDotenv(test.Env)
+		if err == nil || !strings.HasPrefix(err.Error(), test.Error) {
+			t.Errorf("Expected the error %q encoding %v. Got %v\n", test.Error, test.Env, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package formats
+
+import (
+	uni "../interpreter"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/**
+ * Flatten nested configuration data for formats that only hold single values, such as environment variables,
+ * INI files and Java properties.  Each value that is not a map or list is visited along with the path of keys
+ * and list indices leading to it, in sorted order, so that the output of every format is deterministic.
+ * Empty maps and lists contain no values, so they do not appear in flattened output.
+ */
+func flatten(value interface{}, path []string, visit func([]string, interface{}) error) error {
+	switch value.(type) {
+	case map[string]interface{}:
+		table := value.(map[string]interface{})
+		for _, key := range sortedKeys(table) {
+			if err := flatten(table[key], extendPath(path, key), visit); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []interface{}:
+		for i, item := range value.([]interface{}) {
+			if err := flatten(item, extendPath(path, strconv.Itoa(i)), visit); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return visit(path, value)
+}
+
+/**
+ * Add a key to a copy of a path, so that paths held on to by visitors are never changed.
+ */
+func extendPath(path []string, key string) []string {
+	extended := make([]string, len(path), len(path)+1)
+	copy(extended, path)
+	return append(extended, key)
+}
+
+/**
+ * Describe the path to a value, for error messages.  Keys that contain periods are quoted, so that the path to
+ * a key such as "a.b" can be told apart from the path to the key b inside of a map under the key a.
+ */
+func dottedPath(path []string) string {
+	keys := make([]string, len(path))
+	for i, key := range path {
+		if key == "" || strings.Contains(key, ".") {
+			keys[i] = strconv.Quote(key)
+		} else {
+			keys[i] = key
+		}
+	}
+	return strings.Join(keys, ".")
+}
+
+/**
+ * Record the path that produced a flattened key, reporting an error if another path already produced the same
+ * key, since one of the two values would otherwise silently replace the other.  The description names what the
+ * key becomes in the output format, such as "the environment variable A_B".
+ */
+func claimKey(claimed map[string]string, key string, path []string, description string) error {
+	if other, found := claimed[key]; found {
+		errMsg := fmt.Sprintf("The keys %s and %s both become %s.", other, dottedPath(path), description)
+		return errors.New(errMsg)
+	}
+	claimed[key] = dottedPath(path)
+	return nil
+}
+
+/**
+ * Convert a single value to the text written for it in a flattened format.  Nil is written as an empty value.
+ */
+func flatValue(value interface{}) (string, bool) {
+	switch value.(type) {
+	case nil, uni.Nil:
+		return "", true
+	case string:
+		return value.(string), true
+	case int64:
+		return strconv.FormatInt(value.(int64), 10), true
+	case float64:
+		return strconv.FormatFloat(value.(float64), 'g', -1, 64), true
+	case bool:
+		return strconv.FormatBool(value.(bool)), true
+	case uni.Keyword:
+		return value.(uni.Keyword).Contained, true
+	case fmt.Stringer:
+		return value.(fmt.Stringer).String(), true
+	}
+	return "", false
+}
@@ -0,0 +1,321 @@
+package formats
+
+import (
+	uni "../interpreter"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/**
+ * Write configuration data to an HCL file.
+ */
+func WriteHCL(env map[string]interface{}, fileName string) error {
+	encoded, err := EncodeHCL(env)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, encoded, os.ModePerm)
+}
+
+/**
+ * Write configuration data to a Terraform .tfvars file.
+ */
+func WriteTFVars(env map[string]interface{}, fileName string) error {
+	encoded, err := EncodeTFVars(env)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, encoded, os.ModePerm)
+}
+
+/**
+ * Encode configuration data as HCL.  Maps become blocks and lists of maps become repeated blocks, as long as
+ * all of their keys are identifiers, since only identifiers can name the attributes inside of a block.  Other
+ * maps and lists are written as object and tuple expressions.
+ */
+func EncodeHCL(env map[string]interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := writeHCLBody(&buffer, env, "", "", true)
+	return buffer.Bytes(), err
+}
+
+/**
+ * Encode configuration data as a Terraform variable definitions file.  Terraform only accepts attributes in
+ * .tfvars files, so maps and lists are always written as object and tuple expressions.
+ */
+func EncodeTFVars(env map[string]interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := writeHCLBody(&buffer, env, "", "", false)
+	return buffer.Bytes(), err
+}
+
+// Names that can be written without quotes, as attribute names, block types and object keys
+var hclIdentifier = regexp.MustCompile("^[A-Za-z_][A-Za-z0-9_-]*$")
+
+/**
+ * Determine whether a value can be written as a block, or as a sequence of blocks of the same type.
+ */
+func isHCLBlock(value interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}:
+		for key := range value.(map[string]interface{}) {
+			if !hclIdentifier.MatchString(key) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		list := value.([]interface{})
+		if len(list) == 0 {
+			return false
+		}
+		for _, item := range list {
+			if _, isMap := item.(map[string]interface{}); !isMap || !isHCLBlock(item) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+/**
+ * Extend the path to a value with one of its keys, for error messages.
+ */
+func hclPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+/**
+ * Write the attributes of a body, followed by its blocks when they are allowed.
+ */
+func writeHCLBody(buffer *bytes.Buffer, body map[string]interface{}, path, indent string, blocks bool) error {
+	keys := sortedKeys(body)
+	var names, values []string
+	for _, key := range keys {
+		if blocks && isHCLBlock(body[key]) {
+			continue
+		}
+		if !hclIdentifier.MatchString(key) {
+			errMsg := fmt.Sprintf("HCL cannot express the attribute %s, since %q is not an identifier.", hclPath(path, key), key)
+			return errors.New(errMsg)
+		}
+		encoded, err := hclValue(body[key], hclPath(path, key), indent)
+		if err != nil {
+			return err
+		}
+		names = append(names, key)
+		values = append(values, encoded)
+	}
+	writeHCLAttributes(buffer, names, values, indent)
+	if !blocks {
+		return nil
+	}
+	written := len(names) > 0
+	for _, key := range keys {
+		if !isHCLBlock(body[key]) {
+			continue
+		}
+		items := []interface{}{body[key]}
+		list, isList := body[key].([]interface{})
+		if isList {
+			items = list
+		}
+		for i, item := range items {
+			if written {
+				buffer.WriteString("\n")
+			}
+			written = true
+			itemPath := hclPath(path, key)
+			if isList {
+				itemPath = fmt.Sprintf("%s[%d]", itemPath, i)
+			}
+			table := item.(map[string]interface{})
+			if len(table) == 0 {
+				fmt.Fprintf(buffer, "%s%s {}\n", indent, key)
+				continue
+			}
+			fmt.Fprintf(buffer, "%s%s {\n", indent, key)
+			if err := writeHCLBody(buffer, table, itemPath, indent+"  ", true); err != nil {
+				return err
+			}
+			fmt.Fprintf(buffer, "%s}\n", indent)
+		}
+	}
+	return nil
+}
+
+/**
+ * Write attributes or the keys of an object, aligning the equals signs of each group of consecutive attributes the
+ * way `terraform fmt` lays them out.  A group ends with the first attribute whose value spans several lines.
+ */
+func writeHCLAttributes(buffer *bytes.Buffer, names, values []string, indent string) {
+	for i := 0; i < len(names); {
+		end, width := i, 0
+		for end < len(names) {
+			end++
+			if len(names[end-1]) > width {
+				width = len(names[end-1])
+			}
+			if strings.Contains(values[end-1], "\n") {
+				break
+			}
+		}
+		for ; i < end; i++ {
+			fmt.Fprintf(buffer, "%s%-*s = %s\n", indent, width, names[i], values[i])
+		}
+	}
+}
+
+/**
+ * Encode a value written on the right hand side of an attribute, or inside of an object or tuple.  Objects and
+ * tuples containing other objects or tuples are spread over several lines, indented past the line they start on.
+ */
+func hclValue(value interface{}, path, indent string) (string, error) {
+	switch value.(type) {
+	case nil, uni.Nil:
+		return "null", nil
+	case string:
+		return hclString(value.(string)), nil
+	case int64:
+		return strconv.FormatInt(value.(int64), 10), nil
+	case float64:
+		f := value.(float64)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", errors.New(fmt.Sprintf("HCL cannot express the number %v of %s.", f, path))
+		}
+		return strconv.FormatFloat(f, 'g', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(value.(bool)), nil
+	case uni.Keyword:
+		return hclString(value.(uni.Keyword).Contained), nil
+	case fmt.Stringer:
+		return hclString(value.(fmt.Stringer).String()), nil
+	case map[string]interface{}:
+		table := value.(map[string]interface{})
+		if len(table) == 0 {
+			return "{}", nil
+		}
+		var buffer bytes.Buffer
+		buffer.WriteString("{\n")
+		keys := sortedKeys(table)
+		names, values := make([]string, len(keys)), make([]string, len(keys))
+		for i, key := range keys {
+			encoded, err := hclValue(table[key], hclPath(path, key), indent+"  ")
+			if err != nil {
+				return "", err
+			}
+			names[i], values[i] = hclKey(key), encoded
+		}
+		writeHCLAttributes(&buffer, names, values, indent+"  ")
+		buffer.WriteString(indent + "}")
+		return buffer.String(), nil
+	case []interface{}:
+		list := value.([]interface{})
+		items := make([]string, len(list))
+		nested := false
+		for i, item := range list {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				nested = true
+			}
+			// A heredoc's closing delimiter must be alone on its line, leaving no room for a comma
+			if str, isString := item.(string); isString {
+				items[i] = hclQuoted(str)
+				continue
+			}
+			encoded, err := hclValue(item, fmt.Sprintf("%s[%d]", path, i), indent+"  ")
+			if err != nil {
+				return "", err
+			}
+			items[i] = encoded
+		}
+		if !nested {
+			return "[" + strings.Join(items, ", ") + "]", nil
+		}
+		var buffer bytes.Buffer
+		buffer.WriteString("[\n")
+		for _, item := range items {
+			fmt.Fprintf(&buffer, "%s  %s,\n", indent, item)
+		}
+		buffer.WriteString(indent + "]")
+		return buffer.String(), nil
+	}
+	return "", errors.New(fmt.Sprintf("HCL cannot express the value %v of %s.", value, path))
+}
+
+func hclKey(key string) string {
+	if hclIdentifier.MatchString(key) {
+		return key
+	}
+	return hclQuoted(key)
+}
+
+// Escapes for the template sequences HCL would otherwise interpolate in quoted strings and heredocs
+var hclTemplateEscapes = strings.NewReplacer("${", "$${", "%{", "%%{")
+
+/**
+ * Encode a string.  Strings of several lines that end with a line break are written as heredocs, which keep
+ * their contents as they are, and other strings are quoted.  Template sequences are escaped either way, so that
+ * HCL takes them literally.
+ */
+func hclString(str string) string {
+	if strings.Count(str, "\n") > 1 && strings.HasSuffix(str, "\n") && !strings.Contains(str, "\r") {
+		delimiter := "EOT"
+		lines := strings.Split(str, "\n")
+		for i := 0; hclContainsLine(lines, delimiter); i++ {
+			delimiter = "EOT" + strconv.Itoa(i)
+		}
+		return "<<" + delimiter + "\n" + hclTemplateEscapes.Replace(str) + delimiter
+	}
+	return hclQuoted(str)
+}
+
+/**
+ * Encode a string in quotes, escaping line breaks and other control characters along with template sequences.
+ */
+func hclQuoted(str string) string {
+	var buffer bytes.Buffer
+	buffer.WriteByte('"')
+	for _, r := range hclTemplateEscapes.Replace(str) {
+		switch r {
+		case '"':
+			buffer.WriteString("\\\"")
+		case '\\':
+			buffer.WriteString("\\\\")
+		case '\n':
+			buffer.WriteString("\\n")
+		case '\t':
+			buffer.WriteString("\\t")
+		case '\r':
+			buffer.WriteString("\\r")
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&buffer, "\\u%04X", r)
+			} else {
+				buffer.WriteRune(r)
+			}
+		}
+	}
+	buffer.WriteByte('"')
+	return buffer.String()
+}
+
+func hclContainsLine(lines []string, line string) bool {
+	for _, l := range lines {
+		if strings.TrimSpace(l) == line {
+			return true
+		}
+	}
+	return false
+}
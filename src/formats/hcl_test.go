@@ -0,0 +1,129 @@
+package formats
+
+import (
+	uni "../interpreter"
+	"math"
+	"strings"
+	"testing"
+)
+
+var hclTestData = map[string]interface{}{
+	"region":   "eu-west-1",
+	"replicas": int64(3),
+	"ratio":    0.25,
+	"enabled":  true,
+	"owner":    uni.Nil{},
+	"stage":    uni.Keyword{Contained: "prod"},
+	"zones":    []interface{}{"a", "b"},
+	"template": "Hello ${name}\nsee %{if x}\n",
+	"tags":     map[string]interface{}{"team": "core", "cost-center": int64(42), "app.kubernetes.io/name": "api"},
+	"database": map[string]interface{}{"engine": "postgres", "motd": "a \"quoted\"\tline", "pool": map[string]interface{}{}},
+	"listener": []interface{}{
+		map[string]interface{}{"port": int64(80)},
+		map[string]interface{}{"port": int64(443), "ports": []interface{}{[]interface{}{int64(1)}}},
+	},
+}
+
+func TestEncodeHCL(t *testing.T) {
+	expected := `enabled  = true
+owner    = null
+ratio    = 0.25
+region   = "eu-west-1"
+replicas = 3
+stage    = "prod"
+tags     = {
+  "app.kubernetes.io/name" = "api"
+  cost-center              = 42
+  team                     = "core"
+}
+template = <<EOT
+Hello $${name}
+see %%{if x}
+EOT
+zones = ["a", "b"]
+
+database {
+  engine = "postgres"
+  motd   = "a \"quoted\"\tline"
+
+  pool {}
+}
+
+listener {
+  port = 80
+}
+
+listener {
+  port  = 443
+  ports = [
+    [1],
+  ]
+}
+`
+	encoded, err := EncodeHCL(hclTestData)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if string(encoded) != expected {
+		t.Errorf("Encoding HCL produced\n%s\nExpected\n%s\n", encoded, expected)
+	}
+}
+
+func TestEncodeTFVars(t *testing.T) {
+	expected := `database = {
+  engine = "postgres"
+  motd   = "a \"quoted\"\tline"
+  pool   = {}
+}
+enabled  = true
+listener = [
+  {
+    port = 80
+  },
+  {
+    port  = 443
+    ports = [
+      [1],
+    ]
+  },
+]
+owner    = null
+ratio    = 0.25
+region   = "eu-west-1"
+replicas = 3
+stage    = "prod"
+tags     = {
+  "app.kubernetes.io/name" = "api"
+  cost-center              = 42
+  team                     = "core"
+}
+template = <<EOT
+Hello $${name}
+see %%{if x}
+EOT
+zones = ["a", "b"]
+`
+	encoded, err := EncodeTFVars(hclTestData)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if string(encoded) != expected {
+		t.Errorf("Encoding tfvars produced\n%s\nExpected\n%s\n", encoded, expected)
+	}
+}
+
+func TestEncodeHCLErrors(t *testing.T) {
+	tests := []struct {
+		Env   map[string]interface{}
+		Error string
+	}{
+		{map[string]interface{}{"my key": int64(1)}, `HCL cannot express the attribute my key, since "my key" is not an identifier.`},
+		{map[string]interface{}{"a": map[string]interface{}{"b": []interface{}{math.Inf(1)}}}, "HCL cannot express the number +Inf of a.b[0]."},
+	}
+	for _, test := range tests {
+		_, err := EncodeHCL(test.Env)
+		if err == nil || !strings.HasPrefix(err.Error(), test.Error) {
+			t.Errorf("Expected the error %q encoding %v. Got %v\n", test.Error, test.Env, err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package formats
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+/**
+ * Write configuration data to an INI file.
+ */
+func WriteINI(env map[string]interface{}, fileName string) error {
+	encoded, err := EncodeINI(env)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, encoded, os.ModePerm)
+}
+
+/**
+ * Encode configuration data as an INI file.  Each top-level map becomes a section, and the values inside of it
+ * are flattened to dotted keys, so that {"db": {"pool": {"size": 5}}} produces size 5 under pool.size in the
+ * section [db].  Top-level values that are not maps are flattened the same way and written before the first
+ * section, where they are global.
+ */
+func EncodeINI(env map[string]interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	var sections []string
+	globals := map[string]string{}
+	for _, key := range sortedKeys(env) {
+		if _, isMap := env[key].(map[string]interface{}); isMap {
+			sections = append(sections, key)
+			continue
+		}
+		if err := writeINIPairs(&buffer, env[key], []string{key}, 0, globals); err != nil {
+			return nil, err
+		}
+	}
+	for _, section := range sections {
+		if section == "" || strings.ContainsAny(section, "[]\r\n") {
+			return nil, errors.New(fmt.Sprintf("The key %q cannot be written as the name of an INI section.", section))
+		}
+		if buffer.Len() > 0 {
+			buffer.WriteByte('\n')
+		}
+		fmt.Fprintf(&buffer, "[%s]\n", section)
+		if err := writeINIPairs(&buffer, env[section], []string{section}, 1, map[string]string{}); err != nil {
+			return nil, err
+		}
+	}
+	return buffer.Bytes(), nil
+}
+
+/**
+ * Write the key-value pairs flattened out of a value.  The first skip keys of each path name the section the
+ * pairs are written in, so they are left out of the keys.  The keys already written to the section are tracked
+ * so that two values flattening to the same key can be reported.
+ */
+func writeINIPairs(buffer *bytes.Buffer, value interface{}, path []string, skip int, claimed map[string]string) error {
+	return flatten(value, path, func(path []string, value interface{}) error {
+		key := strings.Join(path[skip:], ".")
+		if key == "" || strings.ContainsAny(key, "=;#[]\r\n") || strings.TrimSpace(key) != key {
+			return errors.New(fmt.Sprintf("The key %q of %s cannot be written to an INI file.", key, dottedPath(path)))
+		}
+		str, ok := flatValue(value)
+		if !ok {
+			return errors.New(fmt.Sprintf("The value %v of %s cannot be written to an INI file.", value, dottedPath(path)))
+		}
+		description := "the INI key " + key
+		if skip > 0 {
+			description += " in the section [" + path[0] + "]"
+		}
+		if err := claimKey(claimed, key, path, description); err != nil {
+			return err
+		}
+		if str == "" {
+			// Empty values are written without the space after the equals sign, to leave no trailing whitespace
+			fmt.Fprintf(buffer, "%s =\n", key)
+		} else {
+			fmt.Fprintf(buffer, "%s = %s\n", key, iniValue(str))
+		}
+		return nil
+	})
+}
+
+/**
+ * Quote a value if INI parsers would otherwise change it, by trimming its whitespace, treating part of it as a
+ * comment or ending it at a line break.  Backslashes, double quotes and line breaks inside quotes are escaped.
+ */
+func iniValue(value string) string {
+	if !strings.ContainsAny(value, ";#\"\\\r\n") && strings.TrimSpace(value) == value {
+		return value
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+	return `"` + replacer.Replace(value) + `"`
+}
@@ -0,0 +1,68 @@
+package formats
+
+import (
+	uni "../interpreter"
+	"strings"
+	"testing"
+)
+
+func TestEncodeINI(t *testing.T) {
+	env := map[string]interface{}{
+		"name":  "api",
+		"hosts": []interface{}{"a", "b"},
+		"database": map[string]interface{}{
+			"port":     int64(5432),
+			"password": "  hunter2 ; not a comment",
+			"optional": uni.Nil{},
+			"pool":     map[string]interface{}{"size": int64(5), "ratio": 0.5},
+		},
+		"empty": map[string]interface{}{},
+		"users": map[string]interface{}{
+			"admins": []interface{}{map[string]interface{}{"name": "root", "stage": uni.Keyword{Contained: "prod"}}},
+		},
+	}
+	expected := `hosts.0 = a
+hosts.1 = b
+name = api
+
+[database]
+optional =
+password = "  hunter2 ; not a comment"
+pool.ratio = 0.5
+pool.size = 5
+port = 5432
+
+[empty]
+
+[users]
+admins.0.name = root
+admins.0.stage = prod
+`
+	encoded, err := EncodeINI(env)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if string(encoded) != expected {
+		t.Errorf("Encoding INI produced\n%s\nExpected\n%s\n", encoded, expected)
+	}
+}
+
+func TestEncodeINIErrors(t *testing.T) {
+	tests := []struct {
+		Env   map[string]interface{}
+		Error string
+	}{
+		{map[string]interface{}{"a=b": int64(1)}, `The key "a=b" of a=b cannot be written to an INI file.`},
+		{map[string]interface{}{"db": map[string]interface{}{" x": int64(1)}}, `The key " x" of db. x cannot be written to an INI file.`},
+		{map[string]interface{}{"[db]": map[string]interface{}{}}, `The key "[db]" cannot be written as the name of an INI section.`},
+		{map[string]interface{}{"db": map[string]interface{}{"a.b": int64(1), "a": map[string]interface{}{"b": int64(2)}}},
+			`The keys db.a.b and db."a.b" both become the INI key a.b in the section [db].`},
+		{map[string]interface{}{"hosts.0": "a", "hosts": []interface{}{"b"}}, `The keys hosts.0 and "hosts.0" both become the INI key hosts.0.`},
+	}
+	for _, test := range tests {
+		_, err := EncodeINI(test.Env)
+		if err == nil || !strings.HasPrefix(err.Error(), test.Error) {
+			t.Errorf("Expected the error %q encoding %v. Got %v\n", test.Error, test.Env, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package formats
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+/**
+ * Write configuration data to a Java .properties file.
+ */
+func WriteProperties(env map[string]interface{}, fileName string) error {
+	encoded, err := EncodeProperties(env)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, encoded, os.ModePerm)
+}
+
+/**
+ * Encode configuration data as a Java .properties file.  Nested maps and lists are flattened to dotted keys, so
+ * that {"db": {"hosts": ["a"]}} produces db.hosts.0=a.  Keys and values are escaped the way
+ * java.util.Properties stores them, so the file only contains ASCII and reads back to the same strings.
+ */
+func EncodeProperties(env map[string]interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	claimed := map[string]string{}
+	err := flatten(env, nil, func(path []string, value interface{}) error {
+		str, ok := flatValue(value)
+		if !ok {
+			errMsg := fmt.Sprintf("The value %v of %s cannot be written to a properties file.", value, dottedPath(path))
+			return errors.New(errMsg)
+		}
+		key := strings.Join(path, ".")
+		if err := claimKey(claimed, key, path, "the property "+key); err != nil {
+			return err
+		}
+		fmt.Fprintf(&buffer, "%s=%s\n", propertiesEscaped(key, true), propertiesEscaped(str, false))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+/**
+ * Escape a key or value.  Spaces would end a key or be trimmed from the start of a value, the separators and
+ * comment characters are escaped wherever they appear, and characters outside of printable ASCII are written
+ * as \uXXXX escapes of their UTF-16 code units.
+ */
+func propertiesEscaped(str string, isKey bool) string {
+	var buffer bytes.Buffer
+	for i, r := range str {
+		switch r {
+		case ' ':
+			if isKey || i == 0 {
+				buffer.WriteString("\\ ")
+			} else {
+				buffer.WriteByte(' ')
+			}
+		case '\\', '=', ':', '#', '!':
+			buffer.WriteByte('\\')
+			buffer.WriteRune(r)
+		case '\t':
+			buffer.WriteString("\\t")
+		case '\n':
+			buffer.WriteString("\\n")
+		case '\r':
+			buffer.WriteString("\\r")
+		case '\f':
+			buffer.WriteString("\\f")
+		default:
+			if r >= 0x20 && r <= 0x7e {
+				buffer.WriteRune(r)
+			} else if r > 0xffff {
+				first, second := utf16.EncodeRune(r)
+				fmt.Fprintf(&buffer, "\\u%04X\\u%04X", first, second)
+			} else {
+				fmt.Fprintf(&buffer, "\\u%04X", r)
+			}
+		}
+	}
+	return buffer.String()
+}
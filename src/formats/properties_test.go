@@ -0,0 +1,50 @@
+package formats
+
+import (
+	uni "../interpreter"
+	"testing"
+)
+
+func TestEncodeProperties(t *testing.T) {
+	env := map[string]interface{}{
+		"name":     "api",
+		"greeting": " hello, wörld 🦄",
+		"url":      "http://example.com/#top",
+		"my key":   "a=b",
+		"optional": uni.Nil{},
+		"database": map[string]interface{}{
+			"hosts": []interface{}{"a", "b"},
+			"port":  int64(5432),
+			"motd":  "line one\nline two\\",
+		},
+	}
+	expected := `database.hosts.0=a
+database.hosts.1=b
+database.motd=line one\nline two\\
+database.port=5432
+greeting=\ hello, w\u00F6rld \uD83E\uDD84
+my\ key=a\=b
+name=api
+optional=
+url=http\://example.com/\#top
+`
+	encoded, err := EncodeProperties(env)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if string(encoded) != expected {
+		t.Errorf("Encoding properties produced\n%s\nExpected\n%s\n", encoded, expected)
+	}
+}
+
+func TestEncodePropertiesCollisions(t *testing.T) {
+	env := map[string]interface{}{
+		"db.port": int64(5432),
+		"db":      map[string]interface{}{"port": int64(5433)},
+	}
+	_, err := EncodeProperties(env)
+	expected := `The keys db.port and "db.port" both become the property db.port.`
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected the error %q. Got %v\n", expected, err)
+	}
+}
@@ -0,0 +1,262 @@
+package formats
+
+import (
+	uni "../interpreter"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/**
+ * Write configuration data to a TOML file.
+ */
+func WriteTOML(env map[string]interface{}, fileName string) error {
+	encoded, err := EncodeTOML(env)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, encoded, os.ModePerm)
+}
+
+/**
+ * Encode configuration data as TOML.  Maps become tables and lists of maps become arrays of tables.
+ * TOML cannot express nulls or arrays whose elements have different types, so data containing either is
+ * rejected with an error naming the key it was found under.
+ */
+func EncodeTOML(env map[string]interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := writeTOMLTable(&buffer, env, "")
+	return bytes.TrimPrefix(buffer.Bytes(), []byte("\n")), err
+}
+
+// Keys made up of these characters can be written without quotes
+var bareTOMLKey = regexp.MustCompile("^[A-Za-z0-9_-]+$")
+
+func tomlKey(key string) string {
+	if bareTOMLKey.MatchString(key) {
+		return key
+	}
+	return tomlString(key)
+}
+
+/**
+ * Extend the path to a table with one of its keys, as written in table headers.
+ */
+func tomlPath(path, key string) string {
+	if path == "" {
+		return tomlKey(key)
+	}
+	return path + "." + tomlKey(key)
+}
+
+/**
+ * Determine whether a value is written as a table or an array of tables, rather than as a key-value pair.
+ */
+func isTOMLTable(value interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}:
+		return true
+	case []interface{}:
+		list := value.([]interface{})
+		if len(list) == 0 {
+			return false
+		}
+		for _, item := range list {
+			if _, isMap := item.(map[string]interface{}); !isMap {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func onlyTOMLTables(table map[string]interface{}) bool {
+	for _, value := range table {
+		if !isTOMLTable(value) {
+			return false
+		}
+	}
+	return len(table) > 0
+}
+
+/**
+ * Write the contents of a table.  The key-value pairs of the table are written first, since any that followed
+ * a nested table's header would belong to the nested table, followed by the nested tables and arrays of tables.
+ */
+func writeTOMLTable(buffer *bytes.Buffer, table map[string]interface{}, path string) error {
+	keys := sortedKeys(table)
+	for _, key := range keys {
+		if isTOMLTable(table[key]) {
+			continue
+		}
+		encoded, err := tomlValue(table[key], tomlPath(path, key))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(buffer, "%s = %s\n", tomlKey(key), encoded)
+	}
+	for _, key := range keys {
+		subPath := tomlPath(path, key)
+		switch table[key].(type) {
+		case map[string]interface{}:
+			// Tables containing only other tables are defined by the headers of the tables they contain
+			if !onlyTOMLTables(table[key].(map[string]interface{})) {
+				fmt.Fprintf(buffer, "\n[%s]\n", subPath)
+			}
+			if err := writeTOMLTable(buffer, table[key].(map[string]interface{}), subPath); err != nil {
+				return err
+			}
+		case []interface{}:
+			if !isTOMLTable(table[key]) {
+				continue
+			}
+			for _, item := range table[key].([]interface{}) {
+				fmt.Fprintf(buffer, "\n[[%s]]\n", subPath)
+				if err := writeTOMLTable(buffer, item.(map[string]interface{}), subPath); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+/**
+ * Describe the type of a TOML value, to check that the elements of arrays all have the same type.
+ */
+func tomlType(value interface{}) string {
+	switch value.(type) {
+	case int64:
+		return "integer"
+	case float64:
+		return "float"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "table"
+	}
+	return "string"
+}
+
+/**
+ * Encode a value written on the right hand side of a key-value pair, or in an array.
+ */
+func tomlValue(value interface{}, path string) (string, error) {
+	switch value.(type) {
+	case nil, uni.Nil:
+		return "", errors.New("TOML cannot express the null value of " + path + ".")
+	case string:
+		return tomlString(value.(string)), nil
+	case int64:
+		return strconv.FormatInt(value.(int64), 10), nil
+	case float64:
+		return tomlFloat(value.(float64)), nil
+	case bool:
+		return strconv.FormatBool(value.(bool)), nil
+	case uni.Keyword:
+		return tomlString(value.(uni.Keyword).Contained), nil
+	case fmt.Stringer:
+		return tomlString(value.(fmt.Stringer).String()), nil
+	case map[string]interface{}:
+		// Tables nested inside of arrays of values are written inline
+		table := value.(map[string]interface{})
+		pairs := make([]string, 0, len(table))
+		for _, key := range sortedKeys(table) {
+			encoded, err := tomlValue(table[key], tomlPath(path, key))
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, tomlKey(key)+" = "+encoded)
+		}
+		if len(pairs) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(pairs, ", ") + " }", nil
+	case []interface{}:
+		list := value.([]interface{})
+		items := make([]string, len(list))
+		for i, item := range list {
+			if tomlType(item) != tomlType(list[0]) {
+				errMsg := fmt.Sprintf("TOML cannot express the array %s, which mixes values of type %s and %s.",
+					path, tomlType(list[0]), tomlType(item))
+				return "", errors.New(errMsg)
+			}
+			encoded, err := tomlValue(item, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return "", err
+			}
+			items[i] = encoded
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	}
+	return "", errors.New(fmt.Sprintf("TOML cannot express the value %v of %s.", value, path))
+}
+
+func tomlFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	encoded := strconv.FormatFloat(f, 'g', -1, 64)
+	// TOML floats must have a fractional part or an exponent
+	if !strings.ContainsAny(encoded, ".e") {
+		encoded += ".0"
+	}
+	return encoded
+}
+
+/**
+ * Encode a string as a TOML basic string, escaping quotes, backslashes and control characters.
+ */
+func tomlString(str string) string {
+	var buffer bytes.Buffer
+	buffer.WriteByte('"')
+	for _, r := range str {
+		switch r {
+		case '"':
+			buffer.WriteString("\\\"")
+		case '\\':
+			buffer.WriteString("\\\\")
+		case '\n':
+			buffer.WriteString("\\n")
+		case '\t':
+			buffer.WriteString("\\t")
+		case '\r':
+			buffer.WriteString("\\r")
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&buffer, "\\u%04X", r)
+			} else {
+				buffer.WriteRune(r)
+			}
+		}
+	}
+	buffer.WriteByte('"')
+	return buffer.String()
+}
+
+/**
+ * Produce the keys of a map in sorted order, so that output files are the same each time they are written.
+ */
+func sortedKeys(table map[string]interface{}) []string {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,80 @@
+package formats
+
+import (
+	uni "../interpreter"
+	"strings"
+	"testing"
+)
+
+func TestEncodeTOML(t *testing.T) {
+	env := map[string]interface{}{
+		"name":    "api",
+		"port":    int64(8080),
+		"ratio":   float64(2),
+		"debug":   false,
+		"stage":   uni.Keyword{Contained: "prod"},
+		"tags":    []interface{}{"a", "b \"quoted\""},
+		"empty":   []interface{}{},
+		"my key":  "spaced",
+		"servers": map[string]interface{}{"alpha": map[string]interface{}{"ip": "10.0.0.1"}, "count": int64(1)},
+		"users": []interface{}{
+			map[string]interface{}{"name": "admin", "roles": []interface{}{map[string]interface{}{"id": int64(1)}}},
+			map[string]interface{}{"name": "ci", "limits": map[string]interface{}{"cpu": 0.5}},
+		},
+	}
+	expected := `debug = false
+empty = []
+"my key" = "spaced"
+name = "api"
+port = 8080
+ratio = 2.0
+stage = "prod"
+tags = ["a", "b \"quoted\""]
+
+[servers]
+count = 1
+
+[servers.alpha]
+ip = "10.0.0.1"
+
+[[users]]
+name = "admin"
+
+[[users.roles]]
+id = 1
+
+[[users]]
+name = "ci"
+
+[users.limits]
+cpu = 0.5
+`
+	encoded, err := EncodeTOML(env)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if string(encoded) != expected {
+		t.Errorf("Encoding TOML produced\n%s\nExpected\n%s\n", encoded, expected)
+	}
+}
+
+func TestEncodeTOMLErrors(t *testing.T) {
+	tests := []struct {
+		Env   map[string]interface{}
+		Error string
+	}{
+		{map[string]interface{}{"a": uni.Nil{}}, "TOML cannot express the null value of a."},
+		{map[string]interface{}{"a": map[string]interface{}{"b c": nil}}, `TOML cannot express the null value of a."b c".`},
+		{map[string]interface{}{"a": []interface{}{int64(1), "two"}},
+			"TOML cannot express the array a, which mixes values of type integer and string."},
+		{map[string]interface{}{"a": []interface{}{map[string]interface{}{}, int64(1)}},
+			"TOML cannot express the array a, which mixes values of type table and integer."},
+		{map[string]interface{}{"a": []interface{}{[]interface{}{uni.Nil{}}}}, "TOML cannot express the null value of a[0][0]."},
+	}
+	for _, test := range tests {
+		_, err := EncodeTOML(test.Env)
+		if err == nil || !strings.HasPrefix(err.Error(), test.Error) {
+			t.Errorf("Expected the error %q encoding %v. Got %v\n", test.Error, test.Env, err)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package formats
+
+import (
+	uni "../interpreter"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// The name of the element containing the configuration data, unless another is chosen
+const DefaultXMLRoot = "config"
+
+const (
+	// Keys starting with this prefix are written as attributes of the element for the map containing them
+	XMLAttributePrefix = "@"
+	// The value of this key is written as the text of the element for the map containing it
+	XMLTextKey = "#text"
+)
+
+/**
+ * Write configuration data to an XML file, inside of the default root element.
+ */
+func WriteXML(env map[string]interface{}, fileName string) error {
+	return XMLWriter(DefaultXMLRoot)(env, fileName)
+}
+
+/**
+ * Produce a writer for XML files whose configuration data is inside of a root element with the given name.
+ */
+func XMLWriter(root string) func(map[string]interface{}, string) error {
+	return func(env map[string]interface{}, fileName string) error {
+		encoded, err := EncodeXML(env, root)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(fileName, encoded, os.ModePerm)
+	}
+}
+
+/**
+ * Encode configuration data as an XML document.  Maps become elements containing an element for each of their
+ * keys, and lists become an element repeated for each of their items, so that {"host": ["a", "b"]} produces
+ * <host>a</host><host>b</host>.  Keys starting with @ are written as attributes instead, and the value of the
+ * key #text as the text of the element, so that {"port": {"@protocol": "tcp", "#text": 80}} produces
+ * <port protocol="tcp">80</port>.  Elements and attributes are written in sorted order.
+ */
+func EncodeXML(env map[string]interface{}, root string) ([]byte, error) {
+	if !xmlName.MatchString(root) {
+		return nil, errors.New(fmt.Sprintf("The root element name %q is not a valid XML name.", root))
+	}
+	var buffer bytes.Buffer
+	buffer.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	if err := writeXMLElement(&buffer, root, env, "", ""); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Names that can be given to elements and attributes
+var xmlName = regexp.MustCompile(`^[\pL_:][\pL\pN._:-]*$`)
+
+/**
+ * Extend the path to a value with one of its keys, for error messages.
+ */
+func xmlPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+/**
+ * Write the element, or elements, for a value found under a key.
+ */
+func writeXMLElement(buffer *bytes.Buffer, name string, value interface{}, path, indent string) error {
+	if !xmlName.MatchString(name) {
+		return errors.New(fmt.Sprintf("The key %s cannot be written as an XML element, since %q is not a valid name.",
+			path, name))
+	}
+	switch value.(type) {
+	case []interface{}:
+		for i, item := range value.([]interface{}) {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			if _, isList := item.([]interface{}); isList {
+				errMsg := fmt.Sprintf("XML cannot express the list %s, since it is directly inside of another list.",
+					itemPath)
+				return errors.New(errMsg)
+			}
+			if err := writeXMLElement(buffer, name, item, itemPath, indent); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		return writeXMLMap(buffer, name, value.(map[string]interface{}), path, indent)
+	}
+	text, err := xmlText(value, path)
+	if err != nil {
+		return err
+	}
+	if value == nil || value == (uni.Nil{}) {
+		fmt.Fprintf(buffer, "%s<%s/>\n", indent, name)
+	} else {
+		fmt.Fprintf(buffer, "%s<%s>%s</%s>\n", indent, name, text, name)
+	}
+	return nil
+}
+
+/**
+ * Write the element for a map, with its attributes and either its text or the elements for its other keys.
+ */
+func writeXMLMap(buffer *bytes.Buffer, name string, table map[string]interface{}, path, indent string) error {
+	var attributes, children []string
+	for _, key := range sortedKeys(table) {
+		if strings.HasPrefix(key, XMLAttributePrefix) {
+			attributes = append(attributes, key)
+		} else if key != XMLTextKey {
+			children = append(children, key)
+		}
+	}
+	fmt.Fprintf(buffer, "%s<%s", indent, name)
+	for _, key := range attributes {
+		attribute := strings.TrimPrefix(key, XMLAttributePrefix)
+		if !xmlName.MatchString(attribute) {
+			errMsg := fmt.Sprintf("The key %s cannot be written as an XML attribute, since %q is not a valid name.",
+				xmlPath(path, key), attribute)
+			return errors.New(errMsg)
+		}
+		text, err := xmlText(table[key], xmlPath(path, key))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(buffer, " %s=\"%s\"", attribute, xmlAttributeEscapes.Replace(text))
+	}
+	text, hasText := table[XMLTextKey]
+	switch {
+	case hasText && len(children) > 0:
+		errMsg := fmt.Sprintf("The map %s cannot be written to XML, since it has both %s and other elements.",
+			path, XMLTextKey)
+		return errors.New(errMsg)
+	case hasText:
+		encoded, err := xmlText(text, xmlPath(path, XMLTextKey))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(buffer, ">%s</%s>\n", encoded, name)
+	case len(children) == 0:
+		buffer.WriteString("/>\n")
+	default:
+		buffer.WriteString(">\n")
+		for _, key := range children {
+			if err := writeXMLElement(buffer, key, table[key], xmlPath(path, key), indent+"  "); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(buffer, "%s</%s>\n", indent, name)
+	}
+	return nil
+}
+
+// Escapes for the characters that are special in text, where carriage returns would otherwise be normalized
+// away, and additionally in attribute values, where other whitespace would be normalized to spaces
+var xmlTextEscapes = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+var xmlAttributeEscapes = strings.NewReplacer("\"", "&quot;", "\n", "&#xA;", "\t", "&#x9;")
+
+/**
+ * Encode a value that is not a map or list as escaped text.  Nil is written as no text at all.
+ */
+func xmlText(value interface{}, path string) (string, error) {
+	text, ok := flatValue(value)
+	if !ok {
+		return "", errors.New(fmt.Sprintf("The value of %s cannot be written as XML text or an attribute.", path))
+	}
+	for _, r := range text {
+		if (r < 0x20 && r != '\t' && r != '\n' && r != '\r') || r == 0xfffe || r == 0xffff {
+			errMsg := fmt.Sprintf("XML cannot express the character %U found in the value of %s.", r, path)
+			return "", errors.New(errMsg)
+		}
+	}
+	return xmlTextEscapes.Replace(text), nil
+}
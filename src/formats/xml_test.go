@@ -0,0 +1,76 @@
+package formats
+
+import (
+	uni "../interpreter"
+	"strings"
+	"testing"
+)
+
+func TestEncodeXML(t *testing.T) {
+	env := map[string]interface{}{
+		"@version": int64(2),
+		"name":     "a <b> & c",
+		"optional": uni.Nil{},
+		"stage":    uni.Keyword{Contained: "prod"},
+		"hosts":    []interface{}{"a", "b"},
+		"empty":    []interface{}{},
+		"port":     map[string]interface{}{"@protocol": "tcp", "@note": "say \"hi\"\n", "#text": int64(80)},
+		"database": map[string]interface{}{
+			"ratio":   0.5,
+			"enabled": true,
+			"users":   []interface{}{map[string]interface{}{"@id": int64(1), "name": "root"}, map[string]interface{}{}},
+		},
+	}
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<settings version="2">
+  <database>
+    <enabled>true</enabled>
+    <ratio>0.5</ratio>
+    <users id="1">
+      <name>root</name>
+    </users>
+    <users/>
+  </database>
+  <hosts>a</hosts>
+  <hosts>b</hosts>
+  <name>a &lt;b&gt; &amp; c</name>
+  <optional/>
+  <port note="say &quot;hi&quot;&#xA;" protocol="tcp">80</port>
+  <stage>prod</stage>
+</settings>
+`
+	encoded, err := EncodeXML(env, "settings")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if string(encoded) != expected {
+		t.Errorf("Encoding XML produced\n%s\nExpected\n%s\n", encoded, expected)
+	}
+}
+
+func TestEncodeXMLErrors(t *testing.T) {
+	tests := []struct {
+		Env   map[string]interface{}
+		Root  string
+		Error string
+	}{
+		{map[string]interface{}{}, "my config", `The root element name "my config" is not a valid XML name.`},
+		{map[string]interface{}{"1st": int64(1)}, DefaultXMLRoot,
+			`The key 1st cannot be written as an XML element, since "1st" is not a valid name.`},
+		{map[string]interface{}{"a": map[string]interface{}{"@b c": int64(1)}}, DefaultXMLRoot,
+			`The key a.@b c cannot be written as an XML attribute, since "b c" is not a valid name.`},
+		{map[string]interface{}{"a": map[string]interface{}{"@b": []interface{}{}}}, DefaultXMLRoot,
+			"The value of a.@b cannot be written as XML text or an attribute."},
+		{map[string]interface{}{"a": map[string]interface{}{"#text": "x", "b": "y"}}, DefaultXMLRoot,
+			"The map a cannot be written to XML, since it has both #text and other elements."},
+		{map[string]interface{}{"a": []interface{}{[]interface{}{}}}, DefaultXMLRoot,
+			"XML cannot express the list a[0], since it is directly inside of another list."},
+		{map[string]interface{}{"a": "\x00"}, DefaultXMLRoot, "XML cannot express the character U+0000 found in the value of a."},
+	}
+	for _, test := range tests {
+		_, err := EncodeXML(test.Env, test.Root)
+		if err == nil || !strings.HasPrefix(err.Error(), test.Error) {
+			t.Errorf("Expected the error %q encoding %v. Got %v\n", test.Error, test.Env, err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package interpreter
+
+import (
+	"strings"
+)
+
+/**
+ * An environment is a single scope containing the values bound to names within it.
+ * Scopes are chained together through their parents, so that looking up a name searches the
+ * innermost scope first and then each enclosing scope in turn.  Functions hold on to the
+ * environment they were created in, which gives Fig lexical scoping and proper closures.
+ * Each scope also knows which file its code came from, the loader used to import other files and the
+ * limits on the resources its code may use, all of which are inherited from its parent.
+ */
+type Environment struct {
+	Bindings map[string]Value
+	Parent   *Environment
+	Imports  map[string]*Environment // Imported modules' global scopes, keyed by prefix
+	File     string
+	Loader   *Loader
+	Limits   *Limits
+}
+
+/**
+ * Create a new, empty scope nested inside of a parent scope.  A nil parent creates a global scope.
+ */
+func NewEnvironment(parent *Environment) *Environment {
+	env := &Environment{Bindings: map[string]Value{}, Parent: parent}
+	if parent != nil {
+		env.File = parent.File
+		env.Loader = parent.Loader
+		env.Limits = parent.Limits
+	}
+	return env
+}
+
+/**
+ * Find the value bound to a name in this scope or the nearest enclosing scope that binds it.
+ */
+func (env *Environment) Lookup(name string) (Value, bool) {
+	for scope := env; scope != nil; scope = scope.Parent {
+		if value, found := scope.lookupLocal(name); found {
+			return value, true
+		}
+	}
+	return Value{}, false
+}
+
+/**
+ * Find the value bound to a name in this scope alone.  Names of the form `prefix/name` are also
+ * looked up in the global scope of a module imported into this scope under that prefix.
+ */
+func (env *Environment) lookupLocal(name string) (Value, bool) {
+	if value, found := env.Bindings[name]; found {
+		return value, true
+	}
+	if env.Imports == nil {
+		return Value{}, false
+	}
+	separator := strings.Index(name, "/")
+	if separator <= 0 {
+		return Value{}, false
+	}
+	module, found := env.Imports[name[:separator]]
+	if !found {
+		return Value{}, false
+	}
+	return module.lookupLocal(name[separator+1:])
+}
+
+/**
+ * Bind a name to a value in this scope, shadowing any binding of the same name in enclosing scopes.
+ */
+func (env *Environment) Define(name string, value Value) {
+	env.Bindings[name] = value
+}
+
+/**
+ * Make the definitions in a module's global scope available in this scope under a prefix.
+ */
+func (env *Environment) Import(prefix string, module *Environment) {
+	if env.Imports == nil {
+		env.Imports = map[string]*Environment{}
+	}
+	env.Imports[prefix] = module
+}
@@ -5,22 +5,56 @@ import (
 	"fmt"
 )
 
-// A type that contains information about values in a given scope
-type Environment map[string]Value
+// The name of functions created with `function` until they are bound to a name
+const anonymous = "anonymous"
+
+// The form names given to list and map literals, which cannot be written as names in a program
+const (
+	ListLiteralForm = "[]"
+	MapLiteralForm  = "{}"
+)
 
 /**
  * Simply determines if an S-Expression is one of the supported special forms by checking
  * the name of the function/form to evaulate.
  */
 func isSpecialForm(formName string) bool {
-	return formName == "define" || formName == "if" || formName == "function"
+	switch formName {
+	case "define", "if", "function", "let", "let*", "cond", "case",
+		"quote", "quasiquote", "defmacro", "macroexpand", "try", "import", ListLiteralForm, MapLiteralForm:
+		return true
+	}
+	return false
+}
+
+/**
+ * Determine if an expression is the `else` keyword that introduces the default clause of `cond` and `case`.
+ */
+func isElse(thing interface{}) bool {
+	switch thing.(type) {
+	case Value:
+		value := thing.(Value)
+		return value.Type == NameT && value.Name.Contained == "else"
+	}
+	return false
+}
+
+/**
+ * Give an anonymous function the name it is being bound to, so that errors and stack traces can refer to it.
+ * Functions that already have a name, such as one bound to another name before, keep it.
+ */
+func named(value Value, name string) Value {
+	if value.Type == FunctionT && value.Function.FunctionName.Contained == anonymous {
+		value.Function.FunctionName = Name{name}
+	}
+	return value
 }
 
 /**
  * Evaluate a `define` form to extract the names of variables to assign to, evaluate values,
  * and update the environment.
  */
-func EvaluateDefine(sexp SExpression, env Environment) (error, Value, Environment) {
+func EvaluateDefine(sexp SExpression, env *Environment) (error, Value, *Environment) {
 	// Each value is (or should be) an S-Expression with a name to assign to and a value to evalute
 	var lastValue Value
 	for _, definition := range sexp.Values {
@@ -31,13 +65,13 @@ func EvaluateDefine(sexp SExpression, env Environment) (error, Value, Environmen
 				errMsg := "Definitions must be S-Expressions of the form (name <thing-to-evaluate>)."
 				return errors.New(errMsg), Value{}, env
 			}
-			evalErr, value, newEnv := Evaluate(def.Values[0], env)
+			evalErr, value, _ := Evaluate(def.Values[0], env)
 			if evalErr != nil {
-				return evalErr, value, newEnv
+				return evalErr, value, env
 			}
+			value = named(value, def.FormName.Contained)
 			lastValue = value
-			newEnv[def.FormName.Contained] = value
-			env = newEnv
+			env.Define(def.FormName.Contained, value)
 		default:
 			errMsg := "Pairs of names to assign to and their corresponding values must be contained in S-Expressions."
 			return errors.New(errMsg), Value{}, env
@@ -47,31 +81,193 @@ func EvaluateDefine(sexp SExpression, env Environment) (error, Value, Environmen
 }
 
 /**
- * Evaluate an `if` form to extract and evaluate the condition and then evaluate the appropriate
- * branch expression.
+ * Evaluate the condition of an `if` form and determine which branch expression should be evaluated next.
  */
-func EvaluateIf(sexp SExpression, env Environment) (error, Value, Environment) {
+func selectBranch(sexp SExpression, env *Environment) (error, interface{}) {
 	if len(sexp.Values) != 3 {
-		return errors.New("If expects one condition and two branches."), Value{}, env
+		return errors.New("If expects one condition and two branches."), nil
 	}
-	conditionErr, conditionResult, newEnv := Evaluate(sexp.Values[0], env)
+	conditionErr, conditionResult, _ := Evaluate(sexp.Values[0], env)
 	if conditionErr != nil {
-		return conditionErr, conditionResult, newEnv
+		return conditionErr, nil
 	}
 	if conditionResult.Type != BooleanT {
-		return errors.New("Conditions for branching must evaluate to either true or false."), conditionResult, newEnv
+		return errors.New("Conditions for branching must evaluate to either true or false."), nil
 	}
 	if conditionResult.Boolean.Contained {
-		return Evaluate(sexp.Values[1], newEnv)
+		return nil, sexp.Values[1]
 	} else {
-		return Evaluate(sexp.Values[2], newEnv)
+		return nil, sexp.Values[2]
+	}
+}
+
+/**
+ * Evaluate an `if` form to extract and evaluate the condition and then evaluate the appropriate
+ * branch expression.
+ */
+func EvaluateIf(sexp SExpression, env *Environment) (error, Value, *Environment) {
+	err, branch := selectBranch(sexp, env)
+	if err != nil {
+		return err, Value{}, env
+	}
+	branchErr, value, _ := Evaluate(branch, env)
+	return branchErr, value, env
+}
+
+/**
+ * Evaluate the conditions of a `cond` form in order and determine which expression should be evaluated next.
+ * A `cond` form consists of pairs of conditions and expressions, optionally followed by `else` and a default
+ * expression to use when none of the conditions are true.
+ */
+func selectCondClause(sexp SExpression, env *Environment) (error, interface{}) {
+	if len(sexp.Values) == 0 || len(sexp.Values)%2 != 0 {
+		return errors.New("Cond expects pairs of conditions and expressions."), nil
+	}
+	for i := 0; i < len(sexp.Values); i += 2 {
+		if isElse(sexp.Values[i]) {
+			if i != len(sexp.Values)-2 {
+				return errors.New("The else clause must be the last clause in a cond."), nil
+			}
+			return nil, sexp.Values[i+1]
+		}
+		conditionErr, conditionResult, _ := Evaluate(sexp.Values[i], env)
+		if conditionErr != nil {
+			return conditionErr, nil
+		}
+		if conditionResult.Type != BooleanT {
+			return errors.New("Conditions for branching must evaluate to either true or false."), nil
+		}
+		if conditionResult.Boolean.Contained {
+			return nil, sexp.Values[i+1]
+		}
+	}
+	return errors.New("None of the conditions in cond were true and no else clause was provided."), nil
+}
+
+/**
+ * Evaluate a `cond` form to find the first condition that is true and then evaluate its expression.
+ */
+func EvaluateCond(sexp SExpression, env *Environment) (error, Value, *Environment) {
+	err, clause := selectCondClause(sexp, env)
+	if err != nil {
+		return err, Value{}, env
+	}
+	clauseErr, value, _ := Evaluate(clause, env)
+	return clauseErr, value, env
+}
+
+/**
+ * Evaluate the key of a `case` form and determine which expression should be evaluated next.
+ * A `case` form consists of the key expression followed by pairs of string, integer or keyword literals and
+ * expressions, optionally followed by `else` and a default expression to use when none of the literals equal
+ * the key.
+ */
+func selectCaseClause(sexp SExpression, env *Environment) (error, interface{}) {
+	if len(sexp.Values) < 3 || len(sexp.Values)%2 != 1 {
+		return errors.New("Case expects a key followed by pairs of literals and expressions."), nil
+	}
+	keyErr, key, _ := Evaluate(sexp.Values[0], env)
+	if keyErr != nil {
+		return keyErr, nil
+	}
+	if key.Type != StringT && key.Type != IntegerT && key.Type != KeywordT {
+		return errors.New("Case expects its key to evaluate to a string, an integer or a keyword."), nil
+	}
+	for i := 1; i < len(sexp.Values); i += 2 {
+		if isElse(sexp.Values[i]) {
+			if i != len(sexp.Values)-2 {
+				return errors.New("The else clause must be the last clause in a case."), nil
+			}
+			return nil, sexp.Values[i+1]
+		}
+		var literal Value
+		switch sexp.Values[i].(type) {
+		case Value:
+			literal = sexp.Values[i].(Value)
+		}
+		switch literal.Type {
+		case StringT:
+			if key.Type == StringT && key.String.Contained == literal.String.Contained {
+				return nil, sexp.Values[i+1]
+			}
+		case IntegerT:
+			if key.Type == IntegerT && key.Integer.Contained == literal.Integer.Contained {
+				return nil, sexp.Values[i+1]
+			}
+		case KeywordT:
+			if key.Type == KeywordT && key.Keyword.Contained == literal.Keyword.Contained {
+				return nil, sexp.Values[i+1]
+			}
+		default:
+			return errors.New("Case clauses must start with a string, integer or keyword literal."), nil
+		}
+	}
+	errMsg := fmt.Sprintf("No clause in case matched the key %v and no else clause was provided.", Unwrap(key))
+	return errors.New(errMsg), nil
+}
+
+/**
+ * Evaluate a `case` form to find the clause whose literal equals the key and then evaluate its expression.
+ */
+func EvaluateCase(sexp SExpression, env *Environment) (error, Value, *Environment) {
+	err, clause := selectCaseClause(sexp, env)
+	if err != nil {
+		return err, Value{}, env
+	}
+	clauseErr, value, _ := Evaluate(clause, env)
+	return clauseErr, value, env
+}
+
+/**
+ * Evaluate a `try` form, which evaluates an expression and, if doing so produces an error, evaluates the
+ * handler in its `catch` clause instead.  The handler is evaluated in a new scope in which the name given
+ * in the `catch` clause is bound to a map containing the error's message and any data attached to it.
+ * Errors caused by exceeding the program's limits are never caught.
+ */
+func EvaluateTry(sexp SExpression, env *Environment) (error, Value, *Environment) {
+	errMsg := "Try expects an expression followed by a clause of the form (catch name <handler>)."
+	if len(sexp.Values) != 2 {
+		return errors.New(errMsg), Value{}, env
+	}
+	catch, isSexp := sexp.Values[1].(SExpression)
+	if !isSexp || catch.FormName.Contained != "catch" || len(catch.Values) != 2 {
+		return errors.New(errMsg), Value{}, env
+	}
+	errorName, isValue := catch.Values[0].(Value)
+	if !isValue || errorName.Type != NameT {
+		return errors.New("The error in a catch clause must be bound to a name."), Value{}, env
+	}
+	evalErr, value, _ := Evaluate(sexp.Values[0], env)
+	if evalErr == nil {
+		return nil, value, env
+	}
+	var limitErr LimitError
+	if errors.As(evalErr, &limitErr) {
+		return evalErr, Value{}, env
+	}
+	// The message caught is the description of the error alone, without the position it occurred at
+	var posErr PositionError
+	if errors.As(evalErr, &posErr) {
+		evalErr = posErr.Err
+	}
+	caught := NewMap()
+	caught.Map.Data["message"] = NewString(evalErr.Error())
+	if figErr, isFigErr := evalErr.(FigError); isFigErr {
+		caught.Map.Data["message"] = NewString(figErr.Message)
+		if figErr.HasData {
+			caught.Map.Data["data"] = figErr.Data
+		}
 	}
+	scope := NewEnvironment(env)
+	scope.Define(errorName.Name.Contained, caught)
+	handlerErr, handled, _ := Evaluate(catch.Values[1], scope)
+	return handlerErr, handled, env
 }
 
 /**
  * Evaluate a `function` form to extract the list of argument names and the body expression.
  */
-func EvaluateFunction(sexp SExpression, env Environment) (error, Value, Environment) {
+func EvaluateFunction(sexp SExpression, env *Environment) (error, Value, *Environment) {
 	if len(sexp.Values) != 2 {
 		errMsg := "Function declarations expect one S-Expression with a set of argument names and one with a body."
 		return errors.New(errMsg), Value{}, env
@@ -99,17 +295,114 @@ func EvaluateFunction(sexp SExpression, env Environment) (error, Value, Environm
 			argumentNames = append(argumentNames, argumentList.Values[i].(Value).Name.Contained)
 		}
 	}
-	newFn := NewFunction("tempname", argumentNames, sexp.Values[1])
-	for k, v := range env {
-		newFn.Function.Scope[k] = v
-	}
+	newFn := NewFunction(anonymous, argumentNames, sexp.Values[1])
+	// Capture the scope the function is created in so that it closes over the names visible here
+	newFn.Function.Scope = env
 	return nil, newFn, env
 }
 
+/**
+ * Bind the names in a `let` or `let*` form in a new child scope and extract the body expression to evaluate
+ * in that scope.  With `let`, every value is evaluated in the enclosing scope.  With `let*`, each value is
+ * evaluated in a scope that already contains the names bound before it.
+ */
+func bindLet(sexp SExpression, env *Environment) (error, *Environment, interface{}) {
+	if len(sexp.Values) < 2 {
+		errMsg := "Let expects at least one S-Expression of the form (name <thing-to-evaluate>) followed by a body."
+		return errors.New(errMsg), env, nil
+	}
+	sequential := sexp.FormName.Contained == "let*"
+	scope := NewEnvironment(env)
+	bindings := sexp.Values[:len(sexp.Values)-1]
+	for _, binding := range bindings {
+		switch binding.(type) {
+		case SExpression:
+			def := binding.(SExpression)
+			if len(def.Values) != 1 {
+				errMsg := "Let bindings must be S-Expressions of the form (name <thing-to-evaluate>)."
+				return errors.New(errMsg), env, nil
+			}
+			evalEnv := env
+			if sequential {
+				evalEnv = scope
+			}
+			evalErr, value, _ := Evaluate(def.Values[0], evalEnv)
+			if evalErr != nil {
+				return evalErr, env, nil
+			}
+			scope.Define(def.FormName.Contained, named(value, def.FormName.Contained))
+		default:
+			errMsg := "Pairs of names to bind and their corresponding values must be contained in S-Expressions."
+			return errors.New(errMsg), env, nil
+		}
+	}
+	return nil, scope, sexp.Values[len(sexp.Values)-1]
+}
+
+/**
+ * Evaluate a `let` or `let*` form, which binds names to values only for the duration of the body expression.
+ * The bindings are made in a child scope so that they never leak into the enclosing environment.
+ */
+func EvaluateLet(sexp SExpression, env *Environment) (error, Value, *Environment) {
+	err, scope, body := bindLet(sexp, env)
+	if err != nil {
+		return err, Value{}, env
+	}
+	bodyErr, value, _ := Evaluate(body, scope)
+	return bodyErr, value, env
+}
+
+/**
+ * Evaluate a list literal such as [1 2 3] to produce a list of the values of its elements.
+ */
+func EvaluateListLiteral(sexp SExpression, env *Environment) (error, Value, *Environment) {
+	list := NewList()
+	for _, element := range sexp.Values {
+		err, value, _ := Evaluate(element, env)
+		if err != nil {
+			return err, Value{}, env
+		}
+		list.List.Data = append(list.List.Data, value)
+	}
+	return env.Limits.checkSize(list), list, env
+}
+
+/**
+ * Evaluate a map literal such as {"name" "fig" "version" 1} to produce a map from the value of each key to
+ * the value that follows it.  Keys must be strings or keywords and may only appear once.
+ */
+func EvaluateMapLiteral(sexp SExpression, env *Environment) (error, Value, *Environment) {
+	mapping := NewMap()
+	if len(sexp.Values)%2 == 1 {
+		return errors.New("Map literals must contain an even number of keys and values."), Value{}, env
+	}
+	for i := 0; i < len(sexp.Values); i += 2 {
+		err, key, _ := Evaluate(sexp.Values[i], env)
+		if err != nil {
+			return err, Value{}, env
+		}
+		name, isKey := mapKey(key)
+		if !isKey {
+			err = atPosition(errors.New("Map literal keys must be strings or keywords."), positionOf(sexp.Values[i]))
+			return err, Value{}, env
+		}
+		if _, found := mapping.Map.Data[name]; found {
+			errMsg := "Duplicate key " + name + " in map literal."
+			return atPosition(errors.New(errMsg), positionOf(sexp.Values[i])), Value{}, env
+		}
+		err, value, _ := Evaluate(sexp.Values[i+1], env)
+		if err != nil {
+			return err, Value{}, env
+		}
+		mapping.Map.Data[name] = value
+	}
+	return env.Limits.checkSize(mapping), mapping, env
+}
+
 /**
  * Once a special form is encountered, determine which one it is and call the appropriate evaluator.
  */
-func EvaluateSpecialForm(sexp SExpression, env Environment) (error, Value, Environment) {
+func EvaluateSpecialForm(sexp SExpression, env *Environment) (error, Value, *Environment) {
 	switch sexp.FormName.Contained {
 	case "define":
 		return EvaluateDefine(sexp, env)
@@ -117,6 +410,28 @@ func EvaluateSpecialForm(sexp SExpression, env Environment) (error, Value, Envir
 		return EvaluateIf(sexp, env)
 	case "function":
 		return EvaluateFunction(sexp, env)
+	case "let", "let*":
+		return EvaluateLet(sexp, env)
+	case "cond":
+		return EvaluateCond(sexp, env)
+	case "case":
+		return EvaluateCase(sexp, env)
+	case "quote":
+		return EvaluateQuote(sexp, env)
+	case "quasiquote":
+		return EvaluateQuasiquote(sexp, env)
+	case "defmacro":
+		return EvaluateDefmacro(sexp, env)
+	case "macroexpand":
+		return EvaluateMacroexpand(sexp, env)
+	case "try":
+		return EvaluateTry(sexp, env)
+	case "import":
+		return EvaluateImport(sexp, env)
+	case ListLiteralForm:
+		return EvaluateListLiteral(sexp, env)
+	case MapLiteralForm:
+		return EvaluateMapLiteral(sexp, env)
 	}
 	return errors.New("Unrecognized special form " + sexp.FormName.Contained), Value{}, env
 }
@@ -124,10 +439,10 @@ func EvaluateSpecialForm(sexp SExpression, env Environment) (error, Value, Envir
 /**
  * Evaluate a value by resolving a name to its associated value or just returning the value itself.
  */
-func EvaluateValue(value Value, env Environment) (error, Value, Environment) {
+func EvaluateValue(value Value, env *Environment) (error, Value, *Environment) {
 	if value.Type == NameT {
 		varName := value.Name.Contained
-		actual, found := env[varName]
+		actual, found := env.Lookup(varName)
 		if !found {
 			return errors.New("Variable " + varName + " not assigned."), Value{}, env
 		} else {
@@ -140,78 +455,174 @@ func EvaluateValue(value Value, env Environment) (error, Value, Environment) {
 }
 
 /**
- * Evaluate an S-Expression by evaluating the first name as either a function name or a special form
- * and either applying the successive values as arguments to the function or having the special form handled.
+ * Look up the function named at the start of an S-Expression and evaluate the successive values to
+ * produce the arguments to apply it to.
  */
-func EvaluateSexp(sexp SExpression, env Environment) (error, Value, Environment) {
+func evaluateCall(sexp SExpression, env *Environment) (error, Function, []Value) {
 	fnName := sexp.FormName.Contained
-	function, found := env[fnName]
+	function, found := env.Lookup(fnName)
 	if !found {
-		return errors.New("No such function " + fnName), Value{}, env
+		return errors.New("No such function " + fnName), Function{}, nil
 	}
-	arguments := make([]Value, 0)
+	arguments := make([]Value, 0, len(sexp.Values))
 	for _, arg := range sexp.Values {
-		evalErr, value, newEnv := Evaluate(arg, env)
+		evalErr, value, _ := Evaluate(arg, env)
 		if evalErr != nil {
-			return evalErr, Value{}, newEnv
+			return evalErr, Function{}, nil
 		}
 		arguments = append(arguments, value)
 	}
-	for k, v := range env {
-		function.Function.Scope[k] = v
+	return nil, function.Function, arguments
+}
+
+/**
+ * Evaluate an S-Expression by evaluating the first name as either a function name or a special form
+ * and either applying the successive values as arguments to the function or having the special form handled.
+ */
+func EvaluateSexp(sexp SExpression, env *Environment) (error, Value, *Environment) {
+	err, function, arguments := evaluateCall(sexp, env)
+	if err != nil {
+		return err, Value{}, env
 	}
-	value, err := Apply(function.Function, arguments...)
+	value, err := Apply(function, arguments...)
 	return err, value, env
 }
 
 /**
  * The catch-all evaluate function that determines the type of its contents and invokes the appropriate
  * evaluator for that type.
+ * Expressions in tail position, namely the branches of `if`, `cond` and `case`, the body of a `let`, the
+ * body of a function defined in fig code and the expansion of a macro, are evaluated by looping rather than
+ * recursing so that tail calls run in constant Go stack space.
  */
-func Evaluate(thing interface{}, env Environment) (error, Value, Environment) {
-	switch thing.(type) {
-	case Value:
-		return EvaluateValue(thing.(Value), env)
-	case SExpression:
-		sexp := thing.(SExpression)
-		if isSpecialForm(sexp.FormName.Contained) {
-			return EvaluateSpecialForm(sexp, env)
-		} else {
-			return EvaluateSexp(thing.(SExpression), env)
+func Evaluate(thing interface{}, env *Environment) (error, Value, *Environment) {
+	limits := env.Limits
+	scope := env
+	inFunction := false
+	var pos Position
+	var frame Frame
+	for {
+		// Code produced while the program runs, such as the expansion of a macro, is reported at the
+		// position of the code that produced it
+		if thingPos := positionOf(thing); thingPos.Known() {
+			pos = thingPos
 		}
-	default:
-		return errors.New(fmt.Sprintf("No way to evaluate %v\n", thing)), Value{}, env
+		if err := limits.step(); err != nil {
+			return traced(err, pos, frame), Value{}, env
+		}
+		var err error
+		switch thing.(type) {
+		case Value:
+			valueErr, value, _ := EvaluateValue(thing.(Value), scope)
+			return traced(valueErr, pos, frame), value, env
+		case SExpression:
+			sexp := thing.(SExpression)
+			switch formName := sexp.FormName.Contained; {
+			case formName == "if":
+				err, thing = selectBranch(sexp, scope)
+			case formName == "cond":
+				err, thing = selectCondClause(sexp, scope)
+			case formName == "case":
+				err, thing = selectCaseClause(sexp, scope)
+			case formName == "let" || formName == "let*":
+				err, scope, thing = bindLet(sexp, scope)
+			case isSpecialForm(formName):
+				formErr, value, _ := EvaluateSpecialForm(sexp, scope)
+				return traced(formErr, pos, frame), value, env
+			default:
+				if macro, isMacro := lookupMacro(formName, scope); isMacro {
+					err, thing = expandMacro(macro, sexp)
+					break
+				}
+				var function Function
+				var arguments []Value
+				err, function, arguments = evaluateCall(sexp, scope)
+				if err == nil && function.IsCallable {
+					value, callErr := Apply(function, arguments...)
+					if callErr == nil {
+						callErr = limits.checkSize(value)
+					}
+					return traced(callErr, pos, frame), value, env
+				} else if err == nil {
+					err, scope = bindArguments(function, arguments)
+					thing = function.Body
+					// A tail call replaces the call it is made from, so only the latest appears in stack traces
+					frame = Frame{function.FunctionName.Contained, pos}
+				}
+				// Tail calls replace the function being evaluated, so only the first call adds to the depth
+				if err == nil && !inFunction {
+					if err = limits.enter(); err == nil {
+						inFunction = true
+						defer limits.leave()
+					}
+				}
+			}
+		default:
+			return errors.New(fmt.Sprintf("No way to evaluate %v\n", thing)), Value{}, env
+		}
+		if err != nil {
+			return traced(err, pos, frame), Value{}, env
+		}
+	}
+}
+
+/**
+ * Report an error at the position of the innermost expression that caused it and, if it occurred during a call
+ * to a function defined in fig code, record the call in its stack trace.
+ */
+func traced(err error, pos Position, frame Frame) error {
+	err = atPosition(err, pos)
+	if err != nil && frame.Function != "" {
+		err = inFrame(err, frame)
 	}
+	return err
 }
 
 /**
- * Apply a function to supplied arguments.  If the function was defined in fig code, then the body expression
- * will be evaluated with a new scope relative to the function.
- * The scope will be augmented with the function's argument names so that they are available in deeper
- * scopes.
+ * Create the scope that the body of a function defined in fig code is evaluated in.
+ * The new scope is nested inside of the scope the function was created in and binds the function's
+ * argument names, so each call gets its own bindings and the caller's variables are never visible to
+ * the function.
+ */
+func bindArguments(fn Function, arguments []Value) (error, *Environment) {
+	if len(arguments) < len(fn.ArgumentNames) {
+		return errors.New("Not enough arguments passed to " + fn.FunctionName.Contained), nil
+	}
+	scope := NewEnvironment(fn.Scope)
+	for i, argName := range fn.ArgumentNames {
+		scope.Define(argName.Contained, arguments[i])
+	}
+	return nil, scope
+}
+
+/**
+ * Apply a function to supplied arguments.  If the function is a builtin, the arguments are unwrapped and
+ * passed to the Go code implementing it.  Otherwise, the body expression is evaluated in a new scope
+ * binding the function's arguments.
  */
 func Apply(fn Function, arguments ...Value) (Value, error) {
-	for i := 0; i < len(fn.ArgumentNames); i++ {
-		if i >= len(arguments) {
+	if fn.IsCallable {
+		if len(arguments) < len(fn.ArgumentNames) {
 			return Value{}, errors.New("Not enough arguments passed to " + fn.FunctionName.Contained)
 		}
-		fn.Scope[fn.ArgumentNames[i].Contained] = arguments[i]
-	}
-	var err error
-	var computedValue Value
-	//var newEnv Environment
-	if fn.IsCallable {
 		goValues := make([]interface{}, len(arguments))
 		for i, arg := range arguments {
 			goValues[i] = Unwrap(arg)
+			// Functions are passed to builtins as they are, so that builtins can call them
+			if arg.Type == FunctionT {
+				goValues[i] = arg.Function
+			}
 		}
-		computedValue, err = fn.Call(goValues...)
-	} else {
-		err, computedValue, _ = Evaluate(fn.Body, fn.Scope)
+		return fn.Call(goValues...)
 	}
+	err, scope := bindArguments(fn, arguments)
 	if err != nil {
 		return Value{}, err
 	}
-	err, computedValue, _ = EvaluateValue(computedValue, fn.Scope)
-	return computedValue, err
+	if err = scope.Limits.enter(); err != nil {
+		return Value{}, err
+	}
+	defer scope.Limits.leave()
+	err, computedValue, _ := Evaluate(fn.Body, scope)
+	return computedValue, inFrame(err, Frame{fn.FunctionName.Contained, Position{}})
 }
@@ -1,13 +1,14 @@
 package interpreter
 
 import (
+	"encoding/json"
+	"reflect"
 	"testing"
 )
 
 func TestEvaluateValue(t *testing.T) {
-	env := Environment{
-		"name": NewString("Alice"),
-	}
+	env := NewEnvironment(nil)
+	env.Define("name", NewString("Alice"))
 	// Test that names get mapped to values in an environment
 	err1, value1, newEnv1 := EvaluateValue(NewName("name"), env)
 	if err1 != nil {
@@ -19,7 +20,7 @@ func TestEvaluateValue(t *testing.T) {
 	if value1.String.Contained != "Alice" {
 		t.Error("Expected to get the string `Alice`")
 	}
-	if len(newEnv1) != 1 {
+	if len(newEnv1.Bindings) != 1 {
 		t.Error("Expected no items to be added to or removed from the environment after name evaluation")
 	}
 	// Test that evaluating regular values just gets us back the value
@@ -33,7 +34,7 @@ func TestEvaluateValue(t *testing.T) {
 	if value2.Integer.Contained != 12 {
 		t.Errorf("Expected the integer we found to contain 3. Got %d\n", value2.Integer.Contained)
 	}
-	if len(newEnv2) != 1 {
+	if len(newEnv2.Bindings) != 1 {
 		t.Error("Expected no items to be added to or removed from the environment after integer evaluation")
 	}
 	// Test that if we evaluate a name that isn't in the environment, we get an error
@@ -48,15 +49,14 @@ func TestApply(t *testing.T) {
 		value := args[0].(int64) * args[1].(int64)
 		return NewInteger(value), nil
 	}
-	env := Environment{
-		"mult":   NewCallableFunction("mult", []string{"a", "b"}, mult),
-		"square": NewFunction("square", []string{"a"}, NewSExpression("mult", NewName("a"), NewName("a"))),
-	}
-	// Functions that would have been created by the interpreter must be given information about
-	// the scope that they contain
-	env["square"].Function.Scope["mult"] = env["mult"]
+	env := NewEnvironment(nil)
+	env.Define("mult", NewCallableFunction("mult", []string{"a", "b"}, mult))
+	// Functions that would have been created by the interpreter must be given the scope they were created in
+	square := NewFunction("square", []string{"a"}, NewSExpression("mult", NewName("a"), NewName("a")))
+	square.Function.Scope = env
+	env.Define("square", square)
 	// Test that builtin functions can be invoked to get us a computed result
-	value1, err1 := Apply(env["mult"].Function, NewInteger(10), NewInteger(3))
+	value1, err1 := Apply(env.Bindings["mult"].Function, NewInteger(10), NewInteger(3))
 	if err1 != nil {
 		t.Error(err1.Error())
 	}
@@ -67,7 +67,7 @@ func TestApply(t *testing.T) {
 		t.Error("Expected 10 * 3 to be 30")
 	}
 	// Test that user-defined functions can be reached and a value computed
-	value2, err2 := Apply(env["square"].Function, NewInteger(5))
+	value2, err2 := Apply(env.Bindings["square"].Function, NewInteger(5))
 	if err2 != nil {
 		t.Error(err2.Error())
 	}
@@ -77,6 +77,17 @@ func TestApply(t *testing.T) {
 	if value2.Integer.Contained != 25 {
 		t.Errorf("Expected square(5) to be 25. Got %v\n", value2.Integer.Contained)
 	}
+	// Test that builtins are passed functions they can call
+	applyTo3 := NewCallableFunction("apply-to-3", []string{"f"}, func(args ...interface{}) (Value, error) {
+		return Apply(args[0].(Function), NewInteger(3))
+	})
+	value3, err3 := Apply(applyTo3.Function, square)
+	if err3 != nil {
+		t.Error(err3.Error())
+	}
+	if value3.Integer.Contained != 9 {
+		t.Errorf("Expected a builtin applying square to 3 to produce 9. Got %v\n", value3.Integer.Contained)
+	}
 }
 
 func TestEvaluateSexp(t *testing.T) {
@@ -84,11 +95,10 @@ func TestEvaluateSexp(t *testing.T) {
 		value := args[0].(int64) * args[1].(int64)
 		return NewInteger(value), nil
 	}
-	env := Environment{
-		"a":    NewInteger(4),
-		"b":    NewInteger(2),
-		"mult": NewCallableFunction("mult", []string{"a", "b"}, mult),
-	}
+	env := NewEnvironment(nil)
+	env.Define("a", NewInteger(4))
+	env.Define("b", NewInteger(2))
+	env.Define("mult", NewCallableFunction("mult", []string{"a", "b"}, mult))
 	// (set a 4)
 	// (set b 2)
 	// (mult a b)
@@ -102,7 +112,7 @@ func TestEvaluateSexp(t *testing.T) {
 	if value1.Integer.Contained != 8 {
 		t.Error("Expected 4 * 2 to be 8")
 	}
-	if len(newEnv1) != 3 {
+	if len(newEnv1.Bindings) != 3 {
 		t.Error("Expected no items to be added to or removed from the environment")
 	}
 	err2, _, _ := EvaluateSexp(NewSExpression("add", NewName("a"), NewName("b")), env)
@@ -116,11 +126,10 @@ func TestEvaluate(t *testing.T) {
 		value := args[0].(int64) * args[1].(int64)
 		return NewInteger(value), nil
 	}
-	env := Environment{
-		"a":    NewInteger(-4),
-		"b":    NewInteger(3),
-		"mult": NewCallableFunction("mult", []string{"a", "b"}, mult),
-	}
+	env := NewEnvironment(nil)
+	env.Define("a", NewInteger(-4))
+	env.Define("b", NewInteger(3))
+	env.Define("mult", NewCallableFunction("mult", []string{"a", "b"}, mult))
 	err1, value1, _ := Evaluate(NewName("a"), env)
 	if err1 != nil {
 		t.Error(err1.Error())
@@ -160,4 +169,579 @@ func TestUnwrap(t *testing.T) {
 	if Unwrap(name).(string) != "Alice" {
 		t.Error("Expected unwrapped string to have value 'Alice'")
 	}
+	// Names only show up in data when code is quoted, and must survive being passed through builtins
+	wrapped, err := Wrap(Unwrap(NewName("service")))
+	if err != nil {
+		t.Error(err.Error())
+	}
+	if wrapped.Type != NameT || wrapped.Name.Contained != "service" {
+		t.Error("Expected a name to still be a name after being unwrapped and wrapped again")
+	}
+}
+
+func TestWrapBooleans(t *testing.T) {
+	// Booleans passed through builtins must come back as booleans, so that they can be used as conditions and
+	// are written to output files as booleans rather than as the names true and false
+	for _, b := range []bool{true, false} {
+		wrapped, err := Wrap(Unwrap(NewBoolean(b)))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if wrapped.Type != BooleanT || wrapped.Boolean.Contained != b {
+			t.Errorf("Expected %v to still be a boolean after being unwrapped and wrapped again. Got %v\n", b, wrapped)
+		}
+	}
+	list, err := Wrap([]interface{}{true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	encoded, err := json.Marshal(Unwrap(list))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if string(encoded) != "[true]" {
+		t.Errorf("Expected a wrapped list of booleans to be encoded as [true]. Got %s\n", encoded)
+	}
+}
+
+func TestEvaluateLet(t *testing.T) {
+	add := func(args ...interface{}) (Value, error) {
+		value := args[0].(int64) + args[1].(int64)
+		return NewInteger(value), nil
+	}
+	env := NewEnvironment(nil)
+	env.Define("x", NewInteger(1))
+	env.Define("add", NewCallableFunction("add", []string{"a", "b"}, add))
+	// (let (x 10) (y x) (add x y)) binds y to the outer x
+	letForm := NewSExpression("let",
+		NewSExpression("x", NewInteger(10)),
+		NewSExpression("y", NewName("x")),
+		NewSExpression("add", NewName("x"), NewName("y")))
+	err1, value1, newEnv1 := Evaluate(letForm, env)
+	if err1 != nil {
+		t.Error(err1.Error())
+	}
+	if value1.Integer.Contained != 11 {
+		t.Errorf("Expected let to evaluate bindings in the enclosing scope and produce 11. Got %d\n", value1.Integer.Contained)
+	}
+	if len(newEnv1.Bindings) != 2 || newEnv1.Bindings["x"].Integer.Contained != 1 {
+		t.Error("Expected let bindings not to leak into the enclosing environment")
+	}
+	// (let* (x 10) (y x) (add x y)) binds y to the new x
+	letStarForm := NewSExpression("let*",
+		NewSExpression("x", NewInteger(10)),
+		NewSExpression("y", NewName("x")),
+		NewSExpression("add", NewName("x"), NewName("y")))
+	err2, value2, newEnv2 := Evaluate(letStarForm, env)
+	if err2 != nil {
+		t.Error(err2.Error())
+	}
+	if value2.Integer.Contained != 20 {
+		t.Errorf("Expected let* to evaluate bindings sequentially and produce 20. Got %d\n", value2.Integer.Contained)
+	}
+	if _, found := newEnv2.Bindings["y"]; found {
+		t.Error("Expected let* bindings not to leak into the enclosing environment")
+	}
+	err3, _, _ := Evaluate(NewSExpression("let", NewName("x")), env)
+	if err3 == nil {
+		t.Error("Expected to get an error evaluating a let with no bindings")
+	}
+}
+
+func TestLexicalScope(t *testing.T) {
+	mult := func(args ...interface{}) (Value, error) {
+		value := args[0].(int64) * args[1].(int64)
+		return NewInteger(value), nil
+	}
+	env := NewEnvironment(nil)
+	env.Define("mult", NewCallableFunction("mult", []string{"a", "b"}, mult))
+	// (define (multiplier (function (n) (function (m) (mult n m)))))
+	multiplier := NewSExpression("function", NewSExpression("n"),
+		NewSExpression("function", NewSExpression("m"),
+			NewSExpression("mult", NewName("n"), NewName("m"))))
+	err1, _, _ := Evaluate(NewSExpression("define", NewSExpression("multiplier", multiplier)), env)
+	if err1 != nil {
+		t.Error(err1.Error())
+	}
+	// (define (doubler (multiplier 2)) (tripler (multiplier 3)))
+	err2, _, _ := Evaluate(NewSExpression("define",
+		NewSExpression("doubler", NewSExpression("multiplier", NewInteger(2))),
+		NewSExpression("tripler", NewSExpression("multiplier", NewInteger(3)))), env)
+	if err2 != nil {
+		t.Error(err2.Error())
+	}
+	// Each closure must keep its own binding of n, even though the caller's scope binds n too
+	env.Define("n", NewInteger(100))
+	err3, value3, _ := Evaluate(NewSExpression("doubler", NewInteger(5)), env)
+	if err3 != nil {
+		t.Error(err3.Error())
+	}
+	if value3.Integer.Contained != 10 {
+		t.Errorf("Expected (doubler 5) to be 10. Got %d\n", value3.Integer.Contained)
+	}
+	err4, value4, _ := Evaluate(NewSExpression("tripler", NewInteger(5)), env)
+	if err4 != nil {
+		t.Error(err4.Error())
+	}
+	if value4.Integer.Contained != 15 {
+		t.Errorf("Expected (tripler 5) to be 15. Got %d\n", value4.Integer.Contained)
+	}
+	// Functions must not see variables that are only bound in the scope they are called from
+	peek := NewSExpression("function", NewSExpression("_"), NewName("secret"))
+	Evaluate(NewSExpression("define", NewSExpression("peek", peek)), env)
+	scope := NewEnvironment(env)
+	scope.Define("secret", NewInteger(1))
+	err5, _, _ := Evaluate(NewSExpression("peek"), scope)
+	if err5 == nil {
+		t.Error("Expected a function not to be able to see variables bound in its caller's scope")
+	}
+	if _, found := env.Bindings["m"]; found {
+		t.Error("Expected argument bindings not to leak into the global scope")
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	isZero := func(args ...interface{}) (Value, error) {
+		return NewBoolean(args[0].(int64) == 0), nil
+	}
+	decrement := func(args ...interface{}) (Value, error) {
+		return NewInteger(args[0].(int64) - 1), nil
+	}
+	env := NewEnvironment(nil)
+	env.Define("zero?", NewCallableFunction("zero?", []string{"n"}, isZero))
+	env.Define("dec", NewCallableFunction("dec", []string{"n"}, decrement))
+	// (define (count-down (function (n acc)
+	//   (if (zero? n) acc (let (next (dec n)) (count-down next (dec acc)))))))
+	countDown := NewSExpression("function", NewSExpression("n", NewName("acc")),
+		NewSExpression("if", NewSExpression("zero?", NewName("n")),
+			NewName("acc"),
+			NewSExpression("let", NewSExpression("next", NewSExpression("dec", NewName("n"))),
+				NewSExpression("count-down", NewName("next"), NewSExpression("dec", NewName("acc"))))))
+	err1, _, _ := Evaluate(NewSExpression("define", NewSExpression("count-down", countDown)), env)
+	if err1 != nil {
+		t.Error(err1.Error())
+	}
+	// Recursing a million times would exhaust the Go stack if tail calls were not run in a loop
+	err2, value2, _ := Evaluate(NewSExpression("count-down", NewInteger(1000000), NewInteger(0)), env)
+	if err2 != nil {
+		t.Error(err2.Error())
+	}
+	if value2.Type != IntegerT || value2.Integer.Contained != -1000000 {
+		t.Errorf("Expected (count-down 1000000 0) to be -1000000. Got %d\n", value2.Integer.Contained)
+	}
+}
+
+func TestEvaluateCond(t *testing.T) {
+	equal := func(args ...interface{}) (Value, error) {
+		return NewBoolean(args[0].(string) == args[1].(string)), nil
+	}
+	env := NewEnvironment(nil)
+	env.Define("=", NewCallableFunction("=", []string{"a", "b"}, equal))
+	env.Define("stage", NewString("staging"))
+	// (cond (= stage "dev") 1 (= stage "staging") 2 else 3)
+	condForm := NewSExpression("cond",
+		NewSExpression("=", NewName("stage"), NewString("dev")), NewInteger(1),
+		NewSExpression("=", NewName("stage"), NewString("staging")), NewInteger(2),
+		NewName("else"), NewInteger(3))
+	err1, value1, _ := Evaluate(condForm, env)
+	if err1 != nil {
+		t.Error(err1.Error())
+	}
+	if value1.Integer.Contained != 2 {
+		t.Errorf("Expected cond to select the second clause. Got %d\n", value1.Integer.Contained)
+	}
+	env.Define("stage", NewString("prod"))
+	err2, value2, _ := Evaluate(condForm, env)
+	if err2 != nil {
+		t.Error(err2.Error())
+	}
+	if value2.Integer.Contained != 3 {
+		t.Errorf("Expected cond to fall through to the else clause. Got %d\n", value2.Integer.Contained)
+	}
+	// Without an else clause, failing to match any condition is an error
+	err3, _, _ := Evaluate(NewSExpression("cond",
+		NewSExpression("=", NewName("stage"), NewString("dev")), NewInteger(1)), env)
+	if err3 == nil {
+		t.Error("Expected to get an error when no cond clause matches")
+	}
+	err4, _, _ := Evaluate(NewSExpression("cond", NewName("else"), NewInteger(1), NewBoolean(true), NewInteger(2)), env)
+	if err4 == nil {
+		t.Error("Expected to get an error when the else clause is not the last clause")
+	}
+}
+
+func TestEvaluateCase(t *testing.T) {
+	env := NewEnvironment(nil)
+	env.Define("stage", NewString("canary"))
+	env.Define("replicas", NewInteger(3))
+	// (case stage "dev" 1 "canary" 2 else 3)
+	err1, value1, _ := Evaluate(NewSExpression("case", NewName("stage"),
+		NewString("dev"), NewInteger(1),
+		NewString("canary"), NewInteger(2),
+		NewName("else"), NewInteger(3)), env)
+	if err1 != nil {
+		t.Error(err1.Error())
+	}
+	if value1.Integer.Contained != 2 {
+		t.Errorf("Expected case to select the canary clause. Got %d\n", value1.Integer.Contained)
+	}
+	// (case replicas 1 "single" 3 "triple")
+	err2, value2, _ := Evaluate(NewSExpression("case", NewName("replicas"),
+		NewInteger(1), NewString("single"),
+		NewInteger(3), NewString("triple")), env)
+	if err2 != nil {
+		t.Error(err2.Error())
+	}
+	if value2.String.Contained != "triple" {
+		t.Errorf("Expected case to select the triple clause. Got %s\n", value2.String.Contained)
+	}
+	// Strings and integers never equal one another
+	err3, _, _ := Evaluate(NewSExpression("case", NewName("replicas"), NewString("3"), NewInteger(1)), env)
+	if err3 == nil {
+		t.Error("Expected to get an error when no case clause matches")
+	}
+	err4, _, _ := Evaluate(NewSExpression("case", NewName("stage"), NewName("stage"), NewInteger(1)), env)
+	if err4 == nil {
+		t.Error("Expected to get an error when a case clause does not start with a literal")
+	}
+}
+
+func TestQuoting(t *testing.T) {
+	env := NewEnvironment(nil)
+	env.Define("port", NewInteger(8080))
+	hosts := NewList()
+	hosts.List.Data = []Value{NewString("a"), NewString("b")}
+	env.Define("hosts", hosts)
+	// (quote (service port)) produces the list [service port] without looking up port
+	err1, value1, _ := Evaluate(NewSExpression("quote", NewSExpression("service", NewName("port"))), env)
+	if err1 != nil {
+		t.Error(err1.Error())
+	}
+	if value1.Type != ListT || len(value1.List.Data) != 2 {
+		t.Fatal("Expected quoting an S-Expression to produce a list of two values")
+	}
+	if value1.List.Data[1].Type != NameT || value1.List.Data[1].Name.Contained != "port" {
+		t.Error("Expected names in quoted code to be left as names")
+	}
+	// (quasiquote (service (unquote port) (unquote-splicing hosts)))
+	err2, value2, _ := Evaluate(NewSExpression("quasiquote", NewSExpression("service",
+		NewSExpression("unquote", NewName("port")),
+		NewSExpression("unquote-splicing", NewName("hosts")))), env)
+	if err2 != nil {
+		t.Error(err2.Error())
+	}
+	if len(value2.List.Data) != 4 {
+		t.Fatalf("Expected quasiquoting to splice in two hosts and produce four values. Got %d\n", len(value2.List.Data))
+	}
+	if value2.List.Data[1].Type != IntegerT || value2.List.Data[1].Integer.Contained != 8080 {
+		t.Error("Expected unquoted expressions to be evaluated")
+	}
+	if value2.List.Data[3].String.Contained != "b" {
+		t.Error("Expected unquote-splicing to insert the elements of the list")
+	}
+}
+
+func TestQuotingLiterals(t *testing.T) {
+	env := NewEnvironment(nil)
+	env.Define("port", NewInteger(8080))
+	// (quote [1 port]) produces the list [1 port] rather than [[] 1 port]
+	err1, value1, _ := Evaluate(NewSExpression("quote", NewSExpression(ListLiteralForm, NewInteger(1), NewName("port"))), env)
+	if err1 != nil {
+		t.Fatal(err1.Error())
+	}
+	if value1.Type != ListT || len(value1.List.Data) != 2 || value1.List.Data[0].Integer.Contained != 1 {
+		t.Fatalf("Expected quoting a list literal to produce the list [1 port]. Got %v\n", Unwrap(value1))
+	}
+	if value1.List.Data[1].Type != NameT || value1.List.Data[1].Name.Contained != "port" {
+		t.Error("Expected names in a quoted list literal to be left as names")
+	}
+	// (quote {"a" [1] :b (x)}) produces a map containing a list and quoted code
+	err2, value2, _ := Evaluate(NewSExpression("quote", NewSExpression(MapLiteralForm,
+		NewString("a"), NewSExpression(ListLiteralForm, NewInteger(1)),
+		NewKeyword("b"), NewSExpression("x"))), env)
+	if err2 != nil {
+		t.Fatal(err2.Error())
+	}
+	expected := map[string]interface{}{"a": []interface{}{int64(1)}, "b": []interface{}{Name{"x"}}}
+	if !reflect.DeepEqual(Unwrap(value2), expected) {
+		t.Errorf("Expected quoting a map literal to produce %v. Got %v\n", expected, Unwrap(value2))
+	}
+	// (quasiquote [(unquote port) {"p" (unquote port)}])
+	err3, value3, _ := Evaluate(NewSExpression("quasiquote", NewSExpression(ListLiteralForm,
+		NewSExpression("unquote", NewName("port")),
+		NewSExpression(MapLiteralForm, NewString("p"), NewSExpression("unquote", NewName("port"))))), env)
+	if err3 != nil {
+		t.Fatal(err3.Error())
+	}
+	expected3 := []interface{}{int64(8080), map[string]interface{}{"p": int64(8080)}}
+	if !reflect.DeepEqual(Unwrap(value3), expected3) {
+		t.Errorf("Expected quasiquoting literals to produce %v. Got %v\n", expected3, Unwrap(value3))
+	}
+	// Quoted map literals must still have string or keyword keys
+	err4, _, _ := Evaluate(NewSExpression("quote", NewSExpression(MapLiteralForm, NewName("a"), NewInteger(1))), env)
+	if err4 == nil {
+		t.Error("Expected quoting a map literal with a name for a key to produce an error")
+	}
+	// Macros are given literals as code, so (defmacro id (x) x) followed by (id [port]) evaluates the literal
+	err5, _, _ := Evaluate(NewSExpression("defmacro", NewName("id"), NewSExpression("x"), NewName("x")), env)
+	if err5 != nil {
+		t.Fatal(err5.Error())
+	}
+	err6, value6, _ := Evaluate(NewSExpression("id", NewSExpression(ListLiteralForm, NewName("port"))), env)
+	if err6 != nil {
+		t.Fatal(err6.Error())
+	}
+	if !reflect.DeepEqual(Unwrap(value6), []interface{}{int64(8080)}) {
+		t.Errorf("Expected (id [port]) to evaluate to [8080]. Got %v\n", Unwrap(value6))
+	}
+}
+
+func TestMacros(t *testing.T) {
+	mult := func(args ...interface{}) (Value, error) {
+		value := args[0].(int64) * args[1].(int64)
+		return NewInteger(value), nil
+	}
+	env := NewEnvironment(nil)
+	env.Define("mult", NewCallableFunction("mult", []string{"a", "b"}, mult))
+	// (defmacro square (x) (quasiquote (mult (unquote x) (unquote x))))
+	err1, _, _ := Evaluate(NewSExpression("defmacro", NewName("square"), NewSExpression("x"),
+		NewSExpression("quasiquote", NewSExpression("mult",
+			NewSExpression("unquote", NewName("x")),
+			NewSExpression("unquote", NewName("x"))))), env)
+	if err1 != nil {
+		t.Error(err1.Error())
+	}
+	// (square (mult 2 3)) expands to (mult (mult 2 3) (mult 2 3))
+	err2, value2, _ := Evaluate(NewSExpression("square", NewSExpression("mult", NewInteger(2), NewInteger(3))), env)
+	if err2 != nil {
+		t.Error(err2.Error())
+	}
+	if value2.Integer.Contained != 36 {
+		t.Errorf("Expected (square (mult 2 3)) to be 36. Got %d\n", value2.Integer.Contained)
+	}
+	// (macroexpand (quote (square y)))
+	err3, value3, _ := Evaluate(NewSExpression("macroexpand",
+		NewSExpression("quote", NewSExpression("square", NewName("y")))), env)
+	if err3 != nil {
+		t.Error(err3.Error())
+	}
+	expansion, isSexp := DataToCode(value3).(SExpression)
+	if !isSexp || expansion.FormName.Contained != "mult" || len(expansion.Values) != 2 {
+		t.Errorf("Expected (square y) to expand to (mult y y). Got %v\n", value3)
+	}
+	if _, found := env.Bindings["y"]; found {
+		t.Error("Expected macro expansion not to evaluate its arguments")
+	}
+}
+
+func TestCollectionLiterals(t *testing.T) {
+	env := NewEnvironment(nil)
+	env.Define("x", NewInteger(2))
+	// [1 x [x]]
+	err1, value1, _ := Evaluate(NewSExpression(ListLiteralForm,
+		NewInteger(1), NewName("x"), NewSExpression(ListLiteralForm, NewName("x"))), env)
+	if err1 != nil {
+		t.Error(err1.Error())
+	}
+	if value1.Type != ListT || len(value1.List.Data) != 3 || value1.List.Data[1].Integer.Contained != 2 ||
+		value1.List.Data[2].Type != ListT {
+		t.Errorf("Expected [1 x [x]] to evaluate to [1 2 [2]]. Got %v\n", Unwrap(value1))
+	}
+	// {"a" x "b" []}
+	err2, value2, _ := Evaluate(NewSExpression(MapLiteralForm,
+		NewString("a"), NewName("x"), NewString("b"), NewSExpression(ListLiteralForm)), env)
+	if err2 != nil {
+		t.Error(err2.Error())
+	}
+	if value2.Type != MapT || value2.Map.Data["a"].Integer.Contained != 2 || value2.Map.Data["b"].Type != ListT {
+		t.Errorf("Expected {\"a\" x \"b\" []} to evaluate to a map. Got %v\n", Unwrap(value2))
+	}
+	invalid := []SExpression{
+		NewSExpression(MapLiteralForm, NewString("a")),
+		NewSExpression(MapLiteralForm, NewInteger(1), NewInteger(2)),
+		NewSExpression(MapLiteralForm, NewString("a"), NewInteger(1), NewString("a"), NewInteger(2)),
+		NewSExpression(ListLiteralForm, NewName("y")),
+	}
+	for _, sexp := range invalid {
+		if err, _, _ := Evaluate(sexp, env); err == nil {
+			t.Errorf("Expected an error evaluating %v\n", sexp)
+		}
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	env := NewEnvironment(nil)
+	err1, value1, _ := Evaluate(NewKeyword("host"), env)
+	if err1 != nil {
+		t.Error(err1.Error())
+	}
+	if value1.Type != KeywordT || value1.Keyword.Contained != "host" {
+		t.Errorf("Expected :host to evaluate to itself. Got %v\n", value1)
+	}
+	// {:host "localhost" "port" 80}
+	err2, value2, _ := Evaluate(NewSExpression(MapLiteralForm,
+		NewKeyword("host"), NewString("localhost"), NewString("port"), NewInteger(80)), env)
+	if err2 != nil {
+		t.Error(err2.Error())
+	}
+	if value2.Map.Data["host"].String.Contained != "localhost" || value2.Map.Data["port"].Integer.Contained != 80 {
+		t.Errorf("Expected keywords to be used as map keys by name. Got %v\n", Unwrap(value2))
+	}
+	err3, _, _ := Evaluate(NewSExpression(MapLiteralForm,
+		NewKeyword("host"), NewInteger(1), NewString("host"), NewInteger(2)), env)
+	if err3 == nil {
+		t.Error("Expected :host and \"host\" to be the same key in a map literal")
+	}
+	// (case :prod :dev 1 :prod 2)
+	err4, value4, _ := Evaluate(NewSExpression("case",
+		NewKeyword("prod"), NewKeyword("dev"), NewInteger(1), NewKeyword("prod"), NewInteger(2)), env)
+	if err4 != nil {
+		t.Error(err4.Error())
+	}
+	if value4.Integer.Contained != 2 {
+		t.Errorf("Expected case to match the keyword :prod. Got %d\n", value4.Integer.Contained)
+	}
+	wrapped, _ := Wrap(Unwrap(value1))
+	if wrapped.Type != KeywordT || wrapped.Keyword.Contained != "host" {
+		t.Errorf("Expected wrapping an unwrapped keyword to produce the keyword. Got %v\n", wrapped)
+	}
+	encoded, _ := json.Marshal(map[string]interface{}{"key": Unwrap(value1)})
+	if string(encoded) != `{"key":"host"}` {
+		t.Errorf("Expected keywords to be serialized as plain strings. Got %s\n", encoded)
+	}
+}
+
+func TestNil(t *testing.T) {
+	env := NewEnvironment(nil)
+	env.Define("nothing", NewNil())
+	err1, value1, _ := Evaluate(NewName("nothing"), env)
+	if err1 != nil {
+		t.Error(err1.Error())
+	}
+	if value1.Type != NilT {
+		t.Errorf("Expected a name bound to nil to evaluate to nil. Got %v\n", value1)
+	}
+	if err2, _, _ := Evaluate(NewName("undefined"), env); err2 == nil {
+		t.Error("Expected an undefined name to be an error rather than nil")
+	}
+	list := NewList()
+	list.List.Data = append(list.List.Data, NewInteger(1), NewNil())
+	unwrapped := Unwrap(list)
+	if _, isNil := unwrapped.([]interface{})[1].(Nil); !isNil {
+		t.Errorf("Expected nil to unwrap to Nil. Got %v\n", unwrapped)
+	}
+	wrapped, err3 := Wrap(unwrapped)
+	if err3 != nil || wrapped.List.Data[1].Type != NilT {
+		t.Errorf("Expected wrapping an unwrapped nil to produce nil. Got %v\n", wrapped)
+	}
+	if _, err4 := Wrap(nil); err4 == nil {
+		t.Error("Expected values that cannot be unwrapped to still fail to be wrapped")
+	}
+	encoded, _ := json.Marshal(map[string]interface{}{"key": Unwrap(NewNil())})
+	if string(encoded) != `{"key":null}` {
+		t.Errorf("Expected nil to be serialized as null. Got %s\n", encoded)
+	}
+	if marshaled, err5 := Unwrap(NewNil()).(Nil).MarshalYAML(); err5 != nil || marshaled != nil {
+		t.Errorf("Expected nil to be serialized to YAML as null. Got %v\n", marshaled)
+	}
+}
+
+func TestEvaluateTry(t *testing.T) {
+	raise := func(args ...interface{}) (Value, error) {
+		if len(args) == 2 {
+			data, _ := Wrap(args[1])
+			return Value{}, FigError{args[0].(string), data, true}
+		}
+		return Value{}, FigError{Message: args[0].(string)}
+	}
+	env := NewEnvironment(nil)
+	env.Define("raise", NewCallableFunction("raise", []string{"message"}, raise))
+	// (try (raise "port out of range" 70000) (catch e e))
+	err1, value1, _ := Evaluate(NewSExpression("try",
+		NewSExpression("raise", NewString("port out of range"), NewInteger(70000)),
+		NewSExpression("catch", NewName("e"), NewName("e"))), env)
+	if err1 != nil {
+		t.Error(err1.Error())
+	}
+	if value1.Type != MapT || value1.Map.Data["message"].String.Contained != "port out of range" {
+		t.Error("Expected the caught error to be a map containing the error's message")
+	}
+	if value1.Map.Data["data"].Integer.Contained != 70000 {
+		t.Error("Expected the caught error to contain the data attached to it")
+	}
+	if _, found := env.Bindings["e"]; found {
+		t.Error("Expected the caught error not to be bound outside of the handler")
+	}
+	// Errors that do not come from fig code can be caught too
+	err2, value2, _ := Evaluate(NewSExpression("try", NewName("undefined"),
+		NewSExpression("catch", NewName("e"), NewString("fallback"))), env)
+	if err2 != nil {
+		t.Error(err2.Error())
+	}
+	if value2.String.Contained != "fallback" {
+		t.Error("Expected the handler to be evaluated when the expression fails")
+	}
+	// Nothing is caught when the expression succeeds
+	err3, value3, _ := Evaluate(NewSExpression("try", NewInteger(1),
+		NewSExpression("catch", NewName("e"), NewInteger(2))), env)
+	if err3 != nil || value3.Integer.Contained != 1 {
+		t.Error("Expected try to produce the value of an expression that does not fail")
+	}
+	err4, _, _ := Evaluate(NewSExpression("raise", NewString("uncaught")), env)
+	if err4 == nil || err4.Error() != "uncaught" {
+		t.Error("Expected errors raised outside of a try to propagate")
+	}
+	err5, _, _ := Evaluate(NewSExpression("try", NewInteger(1), NewSExpression("rescue", NewName("e"), NewInteger(2))), env)
+	if err5 == nil {
+		t.Error("Expected to get an error when try is not given a catch clause")
+	}
+}
+
+func TestLimits(t *testing.T) {
+	decrement := func(args ...interface{}) (Value, error) {
+		return NewInteger(args[0].(int64) - 1), nil
+	}
+	repeat := func(args ...interface{}) (Value, error) {
+		list := NewList()
+		for i := int64(0); i < args[0].(int64); i++ {
+			list.List.Data = append(list.List.Data, NewInteger(i))
+		}
+		return list, nil
+	}
+	env := NewEnvironment(nil)
+	env.Define("dec", NewCallableFunction("dec", []string{"n"}, decrement))
+	env.Define("repeat", NewCallableFunction("repeat", []string{"n"}, repeat))
+	// (define (forever (function (n) (forever (dec n)))))
+	forever := NewSExpression("function", NewSExpression("n"), NewSExpression("forever", NewSExpression("dec", NewName("n"))))
+	// (define (deep (function (n) (dec (deep (dec n))))))
+	deep := NewSExpression("function", NewSExpression("n"),
+		NewSExpression("dec", NewSExpression("deep", NewSExpression("dec", NewName("n")))))
+	Evaluate(NewSExpression("define", NewSExpression("forever", forever), NewSExpression("deep", deep)), env)
+	env.Limits = &Limits{MaxSteps: 1000, MaxDepth: 50, MaxSize: 10}
+	err1, _, _ := Evaluate(NewSExpression("forever", NewInteger(0)), env)
+	if _, isLimitErr := err1.(LimitError); !isLimitErr {
+		t.Errorf("Expected infinite tail recursion to exceed the step limit. Got %v\n", err1)
+	}
+	env.Limits = &Limits{MaxSteps: 0, MaxDepth: 50, MaxSize: 10}
+	err2, _, _ := Evaluate(NewSExpression("deep", NewInteger(0)), env)
+	if limitErr, isLimitErr := err2.(LimitError); !isLimitErr || limitErr.Max != 50 {
+		t.Errorf("Expected infinite recursion to exceed the depth limit. Got %v\n", err2)
+	}
+	if env.Limits.depth != 0 {
+		t.Errorf("Expected the call depth to return to 0 after an error. Got %d\n", env.Limits.depth)
+	}
+	err3, _, _ := Evaluate(NewSExpression("repeat", NewInteger(11)), env)
+	if _, isLimitErr := err3.(LimitError); !isLimitErr {
+		t.Errorf("Expected a list of 11 elements to exceed the size limit. Got %v\n", err3)
+	}
+	err4, _, _ := Evaluate(NewSExpression("repeat", NewInteger(10)), env)
+	if err4 != nil {
+		t.Error(err4.Error())
+	}
+	// Exceeding a limit cannot be recovered from
+	err5, _, _ := Evaluate(NewSExpression("try", NewSExpression("repeat", NewInteger(11)),
+		NewSExpression("catch", NewName("e"), NewInteger(0))), env)
+	if _, isLimitErr := err5.(LimitError); !isLimitErr {
+		t.Errorf("Expected try not to catch errors caused by exceeding limits. Got %v\n", err5)
+	}
 }
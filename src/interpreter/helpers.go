@@ -18,36 +18,48 @@ var (
 func NewString(str string) Value {
 	emptyl := List{[]Value{}}
 	emptym := Mapping{map[string]Value{}}
-	return Value{StringT, StringLiteral{str}, zeroi, zerof, Name{}, falseb, Function{}, emptyl, emptym, false}
+	return Value{StringT, StringLiteral{str}, zeroi, zerof, Name{}, Keyword{}, falseb, Function{}, emptyl, emptym, false, Position{}}
 }
 
 func NewInteger(n int64) Value {
 	emptyl := List{[]Value{}}
 	emptym := Mapping{map[string]Value{}}
-	return Value{IntegerT, emptys, IntegerLiteral{n}, zerof, Name{}, falseb, Function{}, emptyl, emptym, false}
+	return Value{IntegerT, emptys, IntegerLiteral{n}, zerof, Name{}, Keyword{}, falseb, Function{}, emptyl, emptym, false, Position{}}
 }
 
 func NewFloat(n float64) Value {
 	emptyl := List{[]Value{}}
 	emptym := Mapping{map[string]Value{}}
-	return Value{FloatT, emptys, zeroi, FloatLiteral{n}, Name{}, falseb, Function{}, emptyl, emptym, false}
+	return Value{FloatT, emptys, zeroi, FloatLiteral{n}, Name{}, Keyword{}, falseb, Function{}, emptyl, emptym, false, Position{}}
 }
 
 func NewName(identifier string) Value {
 	emptyl := List{[]Value{}}
 	emptym := Mapping{map[string]Value{}}
-	return Value{NameT, emptys, zeroi, zerof, Name{identifier}, falseb, Function{}, emptyl, emptym, false}
+	return Value{NameT, emptys, zeroi, zerof, Name{identifier}, Keyword{}, falseb, Function{}, emptyl, emptym, false, Position{}}
+}
+
+func NewKeyword(keyword string) Value {
+	emptyl := List{[]Value{}}
+	emptym := Mapping{map[string]Value{}}
+	return Value{KeywordT, emptys, zeroi, zerof, Name{}, Keyword{keyword}, falseb, Function{}, emptyl, emptym, false, Position{}}
+}
+
+func NewNil() Value {
+	emptyl := List{[]Value{}}
+	emptym := Mapping{map[string]Value{}}
+	return Value{NilT, emptys, zeroi, zerof, Name{}, Keyword{}, falseb, Function{}, emptyl, emptym, false, Position{}}
 }
 
 func NewBoolean(value bool) Value {
 	emptyl := List{[]Value{}}
 	emptym := Mapping{map[string]Value{}}
-	return Value{BooleanT, emptys, zeroi, zerof, Name{}, BooleanLiteral{value}, Function{}, emptyl, emptym, false}
+	return Value{BooleanT, emptys, zeroi, zerof, Name{}, Keyword{}, BooleanLiteral{value}, Function{}, emptyl, emptym, false, Position{}}
 }
 
 func NewSExpression(formName string, values ...interface{}) SExpression {
 	emptyArray := make([]interface{}, 0)
-	sexp := SExpression{Name{formName}, SExpressionT, emptyArray}
+	sexp := SExpression{Name{formName}, SExpressionT, emptyArray, Position{}}
 	for _, value := range values {
 		sexp.Values = append(sexp.Values, value)
 	}
@@ -61,7 +73,7 @@ func NewCallableFunction(name string, argNames []string, fn Builtin) Value {
 	}
 	emptyl := List{[]Value{}}
 	emptym := Mapping{map[string]Value{}}
-	return Value{FunctionT, emptys, zeroi, zerof, Name{}, falseb, Function{Name{name}, names, SExpression{}, true, Environment{}, fn}, emptyl, emptym, false}
+	return Value{FunctionT, emptys, zeroi, zerof, Name{}, Keyword{}, falseb, Function{Name{name}, names, SExpression{}, true, nil, fn, false}, emptyl, emptym, false, Position{}}
 }
 
 func NewFunction(name string, argNames []string, body interface{}) Value {
@@ -71,19 +83,33 @@ func NewFunction(name string, argNames []string, body interface{}) Value {
 	}
 	emptyl := List{[]Value{}}
 	emptym := Mapping{map[string]Value{}}
-	return Value{FunctionT, emptys, zeroi, zerof, Name{}, falseb, Function{Name{name}, names, body, false, Environment{}, nil}, emptyl, emptym, false}
+	return Value{FunctionT, emptys, zeroi, zerof, Name{}, Keyword{}, falseb, Function{Name{name}, names, body, false, nil, nil, false}, emptyl, emptym, false, Position{}}
 }
 
 func NewList() Value {
 	emptyl := List{[]Value{}}
 	emptym := Mapping{map[string]Value{}}
-	return Value{ListT, emptys, zeroi, zerof, Name{}, falseb, Function{}, emptyl, emptym, false}
+	return Value{ListT, emptys, zeroi, zerof, Name{}, Keyword{}, falseb, Function{}, emptyl, emptym, false, Position{}}
 }
 
 func NewMap() Value {
 	emptyl := List{[]Value{}}
 	emptym := Mapping{map[string]Value{}}
-	return Value{MapT, emptys, zeroi, zerof, Name{}, falseb, Function{}, emptyl, emptym, false}
+	return Value{MapT, emptys, zeroi, zerof, Name{}, Keyword{}, falseb, Function{}, emptyl, emptym, false, Position{}}
+}
+
+/**
+ * Determine the map key a value stands for.  Maps are keyed by strings, and keywords are used as keys by their
+ * name, so :host and "host" refer to the same entry.
+ */
+func mapKey(value Value) (string, bool) {
+	switch value.Type {
+	case StringT:
+		return value.String.Contained, true
+	case KeywordT:
+		return value.Keyword.Contained, true
+	}
+	return "", false
 }
 
 /**
@@ -98,7 +124,11 @@ func Unwrap(value Value) interface{} {
 	case FloatT:
 		return value.Float.Contained
 	case NameT:
-		return value.Name.Contained
+		return value.Name
+	case KeywordT:
+		return value.Keyword
+	case NilT:
+		return Nil{}
 	case BooleanT:
 		return value.Boolean.Contained
 	case ListT:
@@ -128,12 +158,14 @@ func Wrap(thing interface{}) (Value, error) {
 		return NewFloat(thing.(float64)), nil
 	case string:
 		return NewString(thing.(string)), nil
+	case Name:
+		return NewName(thing.(Name).Contained), nil
+	case Keyword:
+		return NewKeyword(thing.(Keyword).Contained), nil
+	case Nil:
+		return NewNil(), nil
 	case bool:
-		value := thing.(bool)
-		if value {
-			return NewName("true"), nil
-		}
-		return NewName("false"), nil
+		return NewBoolean(thing.(bool)), nil
 	case []interface{}:
 		list := NewList()
 		thingList := thing.([]interface{})
@@ -1,17 +1,19 @@
 package interpreter
 
 import (
-	"errors"
 	"fmt"
-	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
-// States for the Lexer FSM
+// States of the scanner, which record what kind of token is being read
 
 type State int
 
 // NOTE - String1 refers to strings in single-quotes ('), and String2 refers to strings in double-quotes (")
 // We have two states so that we can have one inside the other.
+// Raw refers to raw strings in backticks (`), in which backslashes are not treated as escapes.
 
 const (
 	ERROR   State = iota
@@ -21,160 +23,288 @@ const (
 	COMMENT State = iota
 	NUMBER  State = iota
 	NAME    State = iota
+	RAW     State = iota
 )
 
-/**
- * When lexing a program, certain characters need to be treated as terminators for a particular type of value
- * but should also include that value.  The following instructions dictate what tokens should be added as the
- * FSM moves from character to character through a program.
- */
-
-type Instruction int
-
 const (
-	AddNothing         Instruction = iota
-	AddToken           Instruction = iota
-	AddChar            Instruction = iota
-	AddTokenAndChar    Instruction = iota
-	AddTokenAndEndSexp Instruction = iota
+	unterminatedMsg    = "Unterminated string. Strings must be closed on the line they start on, or written as heredocs."
+	unterminatedRawMsg = "Unterminated raw string. Raw strings must be closed with a backtick."
 )
 
+// Descriptions of where unexpected characters were found, for error messages
+var unexpectedIn = map[State]string{
+	NUMBER: " in a number",
+	NAME:   " in a name",
+}
+
 /**
- * The lexer will recurse when S-Expressions are encountered, so that the FSM can essentially be restarted
- * in a nested S-Expression without having to actually start and manage a new FSM.  These directives tell
- * the lexer when it should recurse, return from a recursed lexing, or continue normally.
+ * The ASCII characters that can appear in names: letters, digits and the symbols !#$%&*+,-./:<=>?@^_
+ * Square brackets are excluded, since they delimit list literals.
  */
+var nameChars = func() [256]bool {
+	var chars [256]bool
+	for c := 0; c < 256; c++ {
+		chars[c] = (c >= 'a' && c <= 'z') || (c >= '*' && c <= '_') || strings.IndexByte("!#$%&", byte(c)) >= 0
+	}
+	chars['['], chars[']'] = false, false
+	return chars
+}()
 
-type RecursiveAction int
+// The tokens for the brackets that open and close list and map literals
+var brackets = map[byte]Token{
+	'[': START_LIST,
+	']': END_LIST,
+	'{': START_MAP,
+	'}': END_MAP,
+}
 
-const (
-	DoNothing RecursiveAction = iota
-	Recurse   RecursiveAction = iota
-	Return    RecursiveAction = iota
-)
+func isCloser(c byte) bool {
+	return c == ']' || c == '}'
+}
 
 /**
- * The following are essentially transition tables for the FSM.
+ * Names may also contain letters, digits and combining marks from any language, but may not start with a
+ * digit or a mark.
  */
+func isNameStart(r rune) bool {
+	if r < utf8.RuneSelf {
+		return nameChars[r]
+	}
+	return unicode.IsLetter(r)
+}
 
-type FSMTransition struct {
-	ReadMatch string
-	WhatToDo  RecursiveAction
-	NextState State
-	WhatToAdd Instruction
-	NewToken  Token
+func isNameChar(r rune) bool {
+	if r < utf8.RuneSelf {
+		return nameChars[r]
+	}
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
 }
 
-var TransitionsFromOpen = [...]FSMTransition{
-	{"\\s", DoNothing, OPEN, AddNothing, NO_TOKEN},
-	{"\\(", Recurse, OPEN, AddToken, START_SEXP},
-	{"'", DoNothing, STRING1, AddToken, START_STRING},
-	{"\"", DoNothing, STRING2, AddToken, START_STRING},
-	{";", DoNothing, COMMENT, AddToken, START_COMMENT},
-	{"[0-9]", DoNothing, NUMBER, AddTokenAndChar, START_NUMBER},
-	{"[0-9a-zA-Z!@#$%^&*-_+=:<,>.?/]", DoNothing, NAME, AddTokenAndChar, START_NAME},
-	{"\\)", Return, OPEN, AddToken, END_SEXP},
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
 }
 
-// Handle single-quoted strings
-var TransitionsFromString1 = [...]FSMTransition{
-	{"\"", DoNothing, STRING1, AddChar, NO_TOKEN},
-	{"'", DoNothing, OPEN, AddToken, END_STRING},
-	{".", DoNothing, STRING1, AddChar, NO_TOKEN},
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
 }
 
-// Handle double-quoted strings
-var TransitionsFromString2 = [...]FSMTransition{
-	{"'", DoNothing, STRING2, AddChar, NO_TOKEN},
-	{"\"", DoNothing, OPEN, AddToken, END_STRING},
-	{".", DoNothing, STRING2, AddChar, NO_TOKEN},
+/**
+ * Determine if a character continues a number, given the part of the number read so far.  Letters are accepted
+ * so that malformed numbers such as 12ab can be reported as a whole when they are parsed, and a sign is accepted
+ * after the exponent marker of a decimal number.
+ */
+func isNumberChar(number string, c byte) bool {
+	if isDigit(c) || c == '.' || c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+		return true
+	}
+	last := number[len(number)-1]
+	isHex := strings.HasPrefix(strings.ToLower(strings.TrimPrefix(number, "-")), "0x")
+	return (c == '-' || c == '+') && (last == 'e' || last == 'E') && !isHex
 }
 
-var TransitionsFromComment = [...]FSMTransition{
-	{"\n", DoNothing, OPEN, AddToken, END_COMMENT},
-	{".", DoNothing, COMMENT, AddNothing, NO_TOKEN},
+/**
+ * The error produced when a program cannot be lexed, such as when it contains a character that cannot appear
+ * where it does or a string that is never closed.
+ */
+type LexError struct {
+	Position Position
+	Char     string // The offending character, if there is one
+	Message  string
 }
 
-var TransitionsFromNumber = [...]FSMTransition{
-	{"\\s", DoNothing, OPEN, AddToken, END_NUMBER},
-	{"\\)", DoNothing, OPEN, AddTokenAndEndSexp, END_NUMBER},
-	{"([0-9]|\\.)", DoNothing, NUMBER, AddChar, NO_TOKEN},
+func (e LexError) Error() string {
+	return e.Message
 }
 
-var TransitionsFromName = [...]FSMTransition{
-	{"\\s", DoNothing, OPEN, AddToken, END_NAME},
-	{"\\)", DoNothing, OPEN, AddTokenAndEndSexp, END_NAME},
-	{"[0-9a-zA-Z!@#$%^&*-_+=:<,>.?/]", DoNothing, NAME, AddChar, NO_TOKEN},
+/**
+ * The tokens produced by lexing a program, along with the position of each one.
+ */
+type scanner struct {
+	tokens    []Token
+	positions []Position
+	pos       Position
+}
+
+func (s *scanner) emit(tokens ...Token) {
+	for _, token := range tokens {
+		s.tokens = append(s.tokens, token)
+		s.positions = append(s.positions, s.pos)
+	}
 }
 
 /**
- * Determine what state to transition into based on the current state and the next characters in the program.
+ * Emit each character of a string as its own token.
  */
-func Transition(state State, read string) (error, State, RecursiveAction, []Token) {
-	var testTransitions []FSMTransition
-	switch state {
-	case OPEN:
-		testTransitions = TransitionsFromOpen[:]
-	case STRING1:
-		testTransitions = TransitionsFromString1[:]
-	case STRING2:
-		testTransitions = TransitionsFromString2[:]
-	case COMMENT:
-		testTransitions = TransitionsFromComment[:]
-	case NUMBER:
-		testTransitions = TransitionsFromNumber[:]
-	case NAME:
-		testTransitions = TransitionsFromName[:]
+func (s *scanner) emitChars(str string) {
+	for i := 0; i < len(str); {
+		_, size := utf8.DecodeRuneInString(str[i:])
+		s.emit(Token(str[i : i+size]))
+		i += size
 	}
-	for _, transition := range testTransitions {
-		matched, err := regexp.MatchString(transition.ReadMatch, read)
-		if err != nil {
-			return err, state, DoNothing, nil
-		} else if matched {
-			nextState := transition.NextState
-			action := transition.WhatToDo
-			var tokens []Token
-			switch transition.WhatToAdd {
-			case AddNothing:
-				tokens = []Token{}
-			case AddToken:
-				tokens = []Token{transition.NewToken}
-			case AddChar:
-				tokens = []Token{Token(read)}
-			case AddTokenAndEndSexp:
-				tokens = []Token{transition.NewToken, END_SEXP}
-			case AddTokenAndChar:
-				tokens = []Token{transition.NewToken, Token(read)}
-			}
-			return nil, nextState, action, tokens
+}
+
+/**
+ * Determine the line and column reached after reading some text from a given line and column.
+ * Columns count characters rather than bytes.
+ */
+func advance(line, column int, text string) (int, int) {
+	for _, r := range text {
+		if r == '\n' {
+			line, column = line+1, 1
+		} else {
+			column++
 		}
 	}
-	// TODO - Provide useful error descriptions
-	errMsg := fmt.Sprintf("No transition from state %d with input %s", state, read)
-	return errors.New(errMsg), ERROR, DoNothing, []Token{}
+	return line, column
 }
 
 /**
- * Lex a program to produce a sequence of tokens and the number of characters read.
+ * Lex a program to produce a sequence of tokens, the position in the program of each token and the number
+ * of characters read.  Positions do not name a file, since the lexer only ever sees the program's source.
+ * The program is scanned in a single pass, one character at a time, with the state recording whether a name,
+ * number, string or comment is being read.  Lexing stops after a closing parenthesis that has no matching
+ * opening parenthesis.
+ * Parentheses delimit S-Expressions, and square brackets and braces delimit list and map literals.  Strings
+ * are written in single or double quotes, in which escape sequences are decoded and which must be closed on the
+ * line they start on, as raw strings in backticks or as heredocs in triple double-quotes.
+ * Each character of a decoded string is emitted as its own token, in the position the string starts at.
+ * A LexError is produced for characters that cannot appear where they do and for strings that are not closed.
  */
-func Lex(program string, startIndex int) ([]Token, int) {
-	tokens := make([]Token, 0)
-	currentState := OPEN
-	for i := startIndex; i < len(program); i++ {
-		char := string(program[i])
-		err, nextState, action, newTokens := Transition(currentState, string(char))
-		if err != nil {
-			panic(err)
+func Lex(program string, startIndex int) (error, []Token, []Position, int) {
+	s := scanner{make([]Token, 0, len(program)-startIndex), make([]Position, 0, len(program)-startIndex), Position{}}
+	line, column := advance(1, 1, program[:startIndex])
+	state := OPEN
+	depth := 0
+	var stringStart Position
+	numberStart := 0
+	for i := startIndex; i < len(program); {
+		r, size := utf8.DecodeRuneInString(program[i:])
+		// Characters are read whole, except for bytes that are not valid UTF-8, which are read one at a time
+		c := program[i]
+		char := Token(program[i : i+size])
+		next := i + size
+		s.pos = Position{"", line, column}
+		nextState := state
+		switch state {
+		case OPEN:
+			switch {
+			case isSpace(c):
+			case c == '(':
+				depth++
+				s.emit(START_SEXP)
+			case c == '\'':
+				nextState = STRING1
+				stringStart = s.pos
+				s.emit(START_STRING)
+			case strings.HasPrefix(program[i:], heredocQuotes):
+				err, contents, end := readHeredoc(program, i)
+				if err != nil {
+					return LexError{s.pos, "", err.Error()}, s.tokens, s.positions, i
+				}
+				s.emit(START_STRING)
+				s.emitChars(contents)
+				s.emit(END_STRING)
+				next = end
+			case c == '`':
+				nextState = RAW
+				stringStart = s.pos
+				s.emit(START_STRING)
+			case c == '"':
+				nextState = STRING2
+				stringStart = s.pos
+				s.emit(START_STRING)
+			case c == ';':
+				nextState = COMMENT
+				s.emit(START_COMMENT)
+			case isDigit(c) || (c == '-' && i+1 < len(program) && isDigit(program[i+1])):
+				nextState = NUMBER
+				numberStart = i
+				s.emit(START_NUMBER, char)
+			case isNameStart(r):
+				nextState = NAME
+				s.emit(START_NAME, char)
+			case c == ')':
+				depth--
+				s.emit(END_SEXP)
+			case brackets[c] != NO_TOKEN:
+				s.emit(brackets[c])
+			default:
+				nextState = ERROR
+			}
+		case STRING1, STRING2:
+			if (state == STRING1 && c == '\'') || (state == STRING2 && c == '"') {
+				nextState = OPEN
+				s.emit(END_STRING)
+			} else if c == '\n' {
+				return LexError{stringStart, "", unterminatedMsg}, s.tokens, s.positions, i
+			} else if c == '\\' {
+				err, decoded, length := unescape(program, i)
+				if err != nil {
+					return LexError{s.pos, string(c), err.Error()}, s.tokens, s.positions, i
+				}
+				s.emitChars(decoded)
+				next = i + length
+			} else {
+				s.emit(char)
+			}
+		case RAW:
+			if c == '`' {
+				nextState = OPEN
+				s.emit(END_STRING)
+			} else {
+				s.emit(char)
+			}
+		case COMMENT:
+			if c == '\n' {
+				nextState = OPEN
+				s.emit(END_COMMENT)
+			}
+		case NUMBER, NAME:
+			end := END_NUMBER
+			if state == NAME {
+				end = END_NAME
+			}
+			if isSpace(c) {
+				nextState = OPEN
+				s.emit(end)
+			} else if c == ')' {
+				nextState = OPEN
+				s.emit(end, END_SEXP)
+			} else if isCloser(c) {
+				nextState = OPEN
+				s.emit(end, brackets[c])
+			} else if state == NUMBER && isNumberChar(program[numberStart:i], c) {
+				s.emit(char)
+			} else if state == NAME && isNameChar(r) {
+				s.emit(char)
+			} else {
+				nextState = ERROR
+			}
+		}
+		if nextState == ERROR {
+			errMsg := fmt.Sprintf("Unexpected character %q%s.", r, unexpectedIn[state])
+			return LexError{s.pos, string(char), errMsg}, s.tokens, s.positions, i
 		}
-		tokens = append(tokens, newTokens...)
-		if action == Recurse {
-			nextTokens, newIndex := Lex(program, i+1)
-			tokens = append(tokens, nextTokens...)
-			i = newIndex
-		} else if action == Return {
-			return tokens, i
+		state = nextState
+		if depth < 0 {
+			return nil, s.tokens, s.positions, i
 		}
-		currentState = nextState
+		line, column = advance(line, column, program[i:next])
+		i = next
+	}
+	// Close whatever was being read when the program ended
+	s.pos = Position{"", line, column}
+	switch state {
+	case STRING1, STRING2:
+		return LexError{stringStart, "", unterminatedMsg}, s.tokens, s.positions, len(program)
+	case RAW:
+		return LexError{stringStart, "", unterminatedRawMsg}, s.tokens, s.positions, len(program)
+	case COMMENT:
+		s.emit(END_COMMENT)
+	case NUMBER:
+		s.emit(END_NUMBER)
+	case NAME:
+		s.emit(END_NAME)
 	}
-	return tokens, len(program)
+	return nil, s.tokens, s.positions, len(program)
 }
@@ -1,132 +1,146 @@
 package interpreter
 
-/**
- * TODO
- * - End numbers and names when ) is read, appending END_NAME (or END_NUMBER) and END_SEXP
- * - Distinguish between ' and " so that one doesn't end the other
- */
-
 import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
 	"regexp"
+	"sort"
+	"strings"
 	"testing"
 )
 
-func TestTransitionsFromOpen(t *testing.T) {
-	tests := [...]string{"\n", "(", "'", "\"", ";", "4", "H", ")"}
-	for i, transition := range TransitionsFromOpen {
-		matched, err := regexp.MatchString(transition.ReadMatch, tests[i])
-		if err != nil {
-			t.Error(err.Error())
-		} else if !matched {
-			t.Errorf("%s did not match %s\n", transition.ReadMatch, tests[i])
-		}
-	}
+/**
+ * When lexing a program, certain characters need to be treated as terminators for a particular type of value
+ * but should also include that value.  The following instructions dictate what tokens should be added as the
+ * FSM moves from character to character through a program.
+ */
+
+type Instruction int
+
+const (
+	AddNothing         Instruction = iota
+	AddToken           Instruction = iota
+	AddChar            Instruction = iota
+	AddTokenAndChar    Instruction = iota
+	AddTokenAndEndSexp Instruction = iota
+)
+
+/**
+ * The lexer will recurse when S-Expressions are encountered, so that the FSM can essentially be restarted
+ * in a nested S-Expression without having to actually start and manage a new FSM.  These directives tell
+ * the lexer when it should recurse, return from a recursed lexing, or continue normally.
+ */
+
+type RecursiveAction int
+
+const (
+	DoNothing RecursiveAction = iota
+	Recurse   RecursiveAction = iota
+	Return    RecursiveAction = iota
+)
+
+/**
+ * The following are essentially transition tables for the FSM.  This is the regexp-driven state machine the
+ * lexer was originally written as, kept here as a reference for the hand-written scanner to be checked and
+ * benchmarked against.
+ */
+
+type FSMTransition struct {
+	ReadMatch string
+	WhatToDo  RecursiveAction
+	NextState State
+	WhatToAdd Instruction
+	NewToken  Token
 }
 
-func TestTransitionsFromString1(t *testing.T) {
-	tests := [...]string{"\"", "'", " "}
-	for i, transition := range TransitionsFromString1 {
-		matched, err := regexp.MatchString(transition.ReadMatch, tests[i])
-		if err != nil {
-			t.Error(err.Error())
-		} else if !matched {
-			t.Errorf("%s did not match %s\n", transition.ReadMatch, tests[i])
-		}
-	}
+var TransitionsFromOpen = [...]FSMTransition{
+	{"\\s", DoNothing, OPEN, AddNothing, NO_TOKEN},
+	{"\\(", Recurse, OPEN, AddToken, START_SEXP},
+	{"'", DoNothing, STRING1, AddToken, START_STRING},
+	{"\"", DoNothing, STRING2, AddToken, START_STRING},
+	{";", DoNothing, COMMENT, AddToken, START_COMMENT},
+	{"[0-9]", DoNothing, NUMBER, AddTokenAndChar, START_NUMBER},
+	{"[0-9a-zA-Z!@#$%^&*-_+=:<,>.?/]", DoNothing, NAME, AddTokenAndChar, START_NAME},
+	{"\\)", Return, OPEN, AddToken, END_SEXP},
 }
 
-func TestTransitionsFromString2(t *testing.T) {
-	tests := [...]string{"'", "\"", "f"}
-	for i, transition := range TransitionsFromString2 {
-		matched, err := regexp.MatchString(transition.ReadMatch, tests[i])
-		if err != nil {
-			t.Error(err.Error())
-		} else if !matched {
-			t.Errorf("%s did not match %s\n", transition.ReadMatch, tests[i])
-		}
-	}
+// Handle single-quoted strings
+var TransitionsFromString1 = [...]FSMTransition{
+	{"\"", DoNothing, STRING1, AddChar, NO_TOKEN},
+	{"'", DoNothing, OPEN, AddToken, END_STRING},
+	{".", DoNothing, STRING1, AddChar, NO_TOKEN},
 }
 
-func TestTransitionsFromComment(t *testing.T) {
-	tests := [...]string{"\n", ";"}
-	for i, transition := range TransitionsFromComment {
-		matched, err := regexp.MatchString(transition.ReadMatch, tests[i])
-		if err != nil {
-			t.Error(err.Error())
-		} else if !matched {
-			t.Errorf("%s did not match %s\n", transition.ReadMatch, tests[i])
-		}
-	}
+// Handle double-quoted strings
+var TransitionsFromString2 = [...]FSMTransition{
+	{"'", DoNothing, STRING2, AddChar, NO_TOKEN},
+	{"\"", DoNothing, OPEN, AddToken, END_STRING},
+	{".", DoNothing, STRING2, AddChar, NO_TOKEN},
 }
 
-func TestTransitionsFromNumber(t *testing.T) {
-	tests := [...]string{"\n", ")", "3"}
-	for i, transition := range TransitionsFromNumber {
-		matched, err := regexp.MatchString(transition.ReadMatch, tests[i])
-		if err != nil {
-			t.Error(err.Error())
-		} else if !matched {
-			t.Errorf("%s did not match %s\n", transition.ReadMatch, tests[i])
-		}
-	}
+var TransitionsFromComment = [...]FSMTransition{
+	{"\n", DoNothing, OPEN, AddToken, END_COMMENT},
+	{".", DoNothing, COMMENT, AddNothing, NO_TOKEN},
 }
 
-func TestTransitionsFromName(t *testing.T) {
-	tests := [...]string{"\t", ")", "_"}
-	for i, transition := range TransitionsFromName {
-		matched, err := regexp.MatchString(transition.ReadMatch, tests[i])
-		if err != nil {
-			t.Error(err.Error())
-		} else if !matched {
-			t.Errorf("%s did not match %s\n", transition.ReadMatch, tests[i])
-		}
-	}
+var TransitionsFromNumber = [...]FSMTransition{
+	{"\\s", DoNothing, OPEN, AddToken, END_NUMBER},
+	{"\\)", DoNothing, OPEN, AddTokenAndEndSexp, END_NUMBER},
+	{"([0-9]|\\.)", DoNothing, NUMBER, AddChar, NO_TOKEN},
 }
 
-func TestTransition(t *testing.T) {
-	tests := [...]struct {
-		From   State
-		To     State
-		Input  string
-		Action RecursiveAction
-		Tokens []Token
-	}{
-		{OPEN, STRING2, "\"", DoNothing, []Token{START_STRING}},
-		{OPEN, STRING1, "'", DoNothing, []Token{START_STRING}},
-		{OPEN, NUMBER, "0", DoNothing, []Token{START_NUMBER, "0"}},
-		{OPEN, OPEN, ")", Return, []Token{END_SEXP}},
-		{OPEN, NAME, "+", DoNothing, []Token{START_NAME, "+"}},
-		{STRING1, OPEN, "'", DoNothing, []Token{END_STRING}},
-		{STRING2, OPEN, "\"", DoNothing, []Token{END_STRING}},
-		{STRING1, STRING1, "\"", DoNothing, []Token{"\""}},
-		{STRING2, STRING2, "'", DoNothing, []Token{"'"}},
-		{COMMENT, OPEN, "\n", DoNothing, []Token{END_COMMENT}},
-		{NUMBER, OPEN, "\t", DoNothing, []Token{END_NUMBER}},
-		{NAME, NAME, "f", DoNothing, []Token{Token("f")}},
-		{NAME, NAME, "?", DoNothing, []Token{Token("?")}},
-		{NAME, OPEN, " ", DoNothing, []Token{END_NAME}},
+var TransitionsFromName = [...]FSMTransition{
+	{"\\s", DoNothing, OPEN, AddToken, END_NAME},
+	{"\\)", DoNothing, OPEN, AddTokenAndEndSexp, END_NAME},
+	{"[0-9a-zA-Z!@#$%^&*-_+=:<,>.?/]", DoNothing, NAME, AddChar, NO_TOKEN},
+}
+
+/**
+ * Determine what state to transition into based on the current state and the next characters in the program.
+ */
+func Transition(state State, read string) (error, State, RecursiveAction, []Token) {
+	var testTransitions []FSMTransition
+	switch state {
+	case OPEN:
+		testTransitions = TransitionsFromOpen[:]
+	case STRING1:
+		testTransitions = TransitionsFromString1[:]
+	case STRING2:
+		testTransitions = TransitionsFromString2[:]
+	case COMMENT:
+		testTransitions = TransitionsFromComment[:]
+	case NUMBER:
+		testTransitions = TransitionsFromNumber[:]
+	case NAME:
+		testTransitions = TransitionsFromName[:]
 	}
-	for _, test := range tests {
-		err, newState, action, tokens := Transition(test.From, test.Input)
+	for _, transition := range testTransitions {
+		matched, err := regexp.MatchString(transition.ReadMatch, read)
 		if err != nil {
-			t.Error(err.Error())
-		}
-		if newState != test.To {
-			t.Errorf("Expected state %d got %d\n", test.To, newState)
-		}
-		if action != test.Action {
-			t.Errorf("Expected action %d got %d\n", test.Action, action)
-		}
-		if len(tokens) != len(test.Tokens) {
-			t.Log(test)
-			t.Errorf("Expected %d tokens got %d\n", len(test.Tokens), len(tokens))
-		}
-		for i, _ := range tokens {
-			if tokens[i] != test.Tokens[i] {
-				t.Errorf("Expected token %s got %s\n", test.Tokens[i], tokens[i])
+			return err, state, DoNothing, nil
+		} else if matched {
+			nextState := transition.NextState
+			action := transition.WhatToDo
+			var tokens []Token
+			switch transition.WhatToAdd {
+			case AddNothing:
+				tokens = []Token{}
+			case AddToken:
+				tokens = []Token{transition.NewToken}
+			case AddChar:
+				tokens = []Token{Token(read)}
+			case AddTokenAndEndSexp:
+				tokens = []Token{transition.NewToken, END_SEXP}
+			case AddTokenAndChar:
+				tokens = []Token{transition.NewToken, Token(read)}
 			}
+			return nil, nextState, action, tokens
 		}
 	}
+	errMsg := fmt.Sprintf("No transition from state %d with input %s", state, read)
+	return errors.New(errMsg), ERROR, DoNothing, []Token{}
 }
 
 func TestLex(t *testing.T) {
@@ -147,10 +161,11 @@ func TestLex(t *testing.T) {
 		{"(t (e) (s 't'))", []Token{START_SEXP, START_NAME, "t", END_NAME, START_SEXP, START_NAME, "e", END_NAME, END_SEXP, START_SEXP, START_NAME, "s", END_NAME, START_STRING, "t", END_STRING, END_SEXP, END_SEXP}},
 		{"(hi 't' (e 'st'))", []Token{START_SEXP, START_NAME, "h", "i", END_NAME, START_STRING, "t", END_STRING, START_SEXP, START_NAME, "e", END_NAME, START_STRING, "s", "t", END_STRING, END_SEXP, END_SEXP}},
 		{"(x) ; test\n", []Token{START_SEXP, START_NAME, "x", END_NAME, END_SEXP, START_COMMENT, END_COMMENT}},
+		{"'\u00e9'", []Token{START_STRING, "\u00e9", END_STRING}},
 		{"(if (x) 3.14 'test')", []Token{START_SEXP, START_NAME, "i", "f", END_NAME, START_SEXP, START_NAME, "x", END_NAME, END_SEXP, START_NUMBER, "3", ".", "1", "4", END_NUMBER, START_STRING, "t", "e", "s", "t", END_STRING, END_SEXP}},
 	}
 	for _, test := range tests {
-		lexed, _ := Lex(test.Program, 0)
+		_, lexed, _, _ := Lex(test.Program, 0)
 		if len(lexed) != len(test.Lexed) {
 			t.Log("Lexing program: " + test.Program)
 			t.Log(lexed)
@@ -166,3 +181,297 @@ func TestLex(t *testing.T) {
 		}
 	}
 }
+
+func TestLexStrings(t *testing.T) {
+	tests := [...]struct {
+		Program  string
+		Expected string
+	}{
+		{`'a\tb\nc'`, "a\tb\nc"},
+		{`"say \"hi\" it\'s \\"`, "say \"hi\" it's \\"},
+		{`'\x41\u00e9\U0001F984'`, "A\u00e9\U0001F984"},
+		{"`C:\\dir\\n\n'raw'`", "C:\\dir\\n\n'raw'"},
+		{"\"\"\"\n    -----BEGIN-----\n      abc\\t\n\n    -----END-----\n    \"\"\"", "-----BEGIN-----\n  abc\t\n\n-----END-----\n"},
+		{`"""one "quoted" line"""`, `one "quoted" line`},
+		{`""`, ""},
+	}
+	for _, test := range tests {
+		err, lexed, _, length := Lex(test.Program, 0)
+		if err != nil {
+			t.Errorf("Lexing %q: %s\n", test.Program, err.Error())
+			continue
+		}
+		parseErr, value, _ := ParseString(lexed, nil, 0)
+		if parseErr != nil {
+			t.Errorf("Parsing %q: %s\n", test.Program, parseErr.Error())
+		} else if value.String.Contained != test.Expected {
+			t.Errorf("Expected %q to be the string %q. Got %q\n", test.Program, test.Expected, value.String.Contained)
+		}
+		if length != len(test.Program) {
+			t.Errorf("Expected to lex all of %q. Lexed %d characters\n", test.Program, length)
+		}
+	}
+	// Lines spanned by heredocs and raw strings are counted in positions
+	_, _, positions, _ := Lex("(x \"\"\"\na\n\"\"\" `b\n` y)", 0)
+	if last := positions[len(positions)-3]; last != (Position{"", 4, 3}) {
+		t.Errorf("Expected the name after multi-line strings to be at line 4, column 3. Got %v\n", last)
+	}
+	invalid := []string{`'\q'`, `'\u12'`, `'\uD800'`, "`never closed", `"""never closed`, `'\`}
+	for _, program := range invalid {
+		if err, _, _, _ := Lex(program, 0); err == nil {
+			t.Errorf("Expected to get an error lexing %q\n", program)
+		}
+	}
+}
+
+func TestLexErrors(t *testing.T) {
+	tests := [...]struct {
+		Program  string
+		Position Position
+		Char     string
+	}{
+		{"(x ~y)", Position{"", 1, 4}, "~"},
+		{"[x y~]", Position{"", 1, 5}, "~"},
+		{"(x\n  ~y)", Position{"", 2, 3}, "~"},
+		{"(x 1$)", Position{"", 1, 5}, "$"},
+		{"(x y')", Position{"", 1, 5}, "'"},
+		{"(x 'abc\n')", Position{"", 1, 4}, ""},
+		{"(x \"abc", Position{"", 1, 4}, ""},
+	}
+	for _, test := range tests {
+		err, _, _, _ := Lex(test.Program, 0)
+		lexErr, isLexErr := err.(LexError)
+		if !isLexErr {
+			t.Errorf("Expected a lex error lexing %q. Got %v\n", test.Program, err)
+		} else if lexErr.Position != test.Position || lexErr.Char != test.Char {
+			t.Errorf("Expected a lex error at %v for %q lexing %q. Got one at %v for %q\n",
+				test.Position, test.Char, test.Program, lexErr.Position, lexErr.Char)
+		}
+	}
+	// Comments, names and numbers at the end of a program are ended
+	ends := map[string][]Token{
+		";c":  {START_COMMENT, END_COMMENT},
+		"x":   {START_NAME, "x", END_NAME},
+		"1.5": {START_NUMBER, "1", ".", "5", END_NUMBER},
+	}
+	for program, expected := range ends {
+		err, lexed, _, _ := Lex(program, 0)
+		if err != nil {
+			t.Error(err.Error())
+		}
+		if !reflect.DeepEqual(lexed, expected) {
+			t.Errorf("Expected lexing %q to produce %v. Got %v\n", program, expected, lexed)
+		}
+	}
+}
+
+func TestLexPositions(t *testing.T) {
+	program := "(x\n  'hi')"
+	_, lexed, positions, _ := Lex(program, 0)
+	if len(positions) != len(lexed) {
+		t.Fatalf("Expected a position for each of the %d tokens. Got %d\n", len(lexed), len(positions))
+	}
+	expected := []Position{{"", 1, 1}, {"", 1, 2}, {"", 1, 2}, {"", 1, 3}, {"", 2, 3}, {"", 2, 4}, {"", 2, 5}, {"", 2, 6}, {"", 2, 7}}
+	for i, pos := range expected {
+		if positions[i] != pos {
+			t.Errorf("Expected token %s to be at %v. Got %v\n", lexed[i], pos, positions[i])
+		}
+	}
+}
+
+/**
+ * Find the index in a program at which each of its lines starts, so that indices can be turned into positions.
+ */
+func lineStarts(program string) []int {
+	starts := []int{0}
+	for i := 0; i < len(program); i++ {
+		if program[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+/**
+ * Determine the line and column of the character at an index in a program, given where each line starts.
+ */
+func positionAt(starts []int, index int) Position {
+	line := sort.SearchInts(starts, index+1) - 1
+	return Position{"", line + 1, index - starts[line] + 1}
+}
+
+/**
+ * The lexer as it was before the hand-written scanner, driven by the transition tables.
+ * It is kept here to check that the scanner produces the same tokens and to compare their performance.
+ */
+func lexWithTransitions(program string, startIndex int, starts []int) ([]Token, []Position, int) {
+	tokens := make([]Token, 0)
+	positions := make([]Position, 0)
+	currentState := OPEN
+	for i := startIndex; i < len(program); i++ {
+		char := string(program[i])
+		err, nextState, action, newTokens := Transition(currentState, string(char))
+		if err != nil {
+			panic(err)
+		}
+		tokens = append(tokens, newTokens...)
+		for _ = range newTokens {
+			positions = append(positions, positionAt(starts, i))
+		}
+		if action == Recurse {
+			nextTokens, nextPositions, newIndex := lexWithTransitions(program, i+1, starts)
+			tokens = append(tokens, nextTokens...)
+			positions = append(positions, nextPositions...)
+			i = newIndex
+		} else if action == Return {
+			return tokens, positions, i
+		}
+		currentState = nextState
+	}
+	return tokens, positions, len(program)
+}
+
+/**
+ * Lex a program with the old lexer, recovering from the panic it raised on invalid programs.
+ * The old lexer stopped silently at the end of an unterminated string, which is also treated as a failure.
+ */
+func tryLexWithTransitions(program string) (tokens []Token, positions []Position, length int, failed bool) {
+	defer func() {
+		if recover() != nil {
+			failed = true
+		}
+	}()
+	tokens, positions, length = lexWithTransitions(program, 0, lineStarts(program))
+	unclosed := 0
+	for _, token := range tokens {
+		if token == START_STRING {
+			unclosed++
+		} else if token == END_STRING {
+			unclosed--
+		}
+	}
+	return tokens, positions, length, unclosed != 0
+}
+
+func TestNameChars(t *testing.T) {
+	pattern := TransitionsFromName[len(TransitionsFromName)-1].ReadMatch
+	for c := 0; c < 256; c++ {
+		matched, _ := regexp.MatchString(pattern, string([]byte{byte(c)}))
+		if c == '[' || c == ']' {
+			// Square brackets were name characters before list literals were added
+			matched = false
+		}
+		if matched != nameChars[c] {
+			t.Errorf("Expected %q being a name character to be %v\n", byte(c), matched)
+		}
+	}
+}
+
+func TestLexMatchesTransitions(t *testing.T) {
+	programs := []string{
+		"(define (x 1)) ; comment\n(y (+ x 2.5))",
+		"(a (b (c)))) (d)",
+		"('it\"s' \"don't\")\n\t(f\r\n  g)",
+		"(x 1a)",
+		"(x 'unterminated\nstring')",
+		"(s\n",
+	}
+	// Letters in numbers and negative numbers were not supported by the old lexer
+	newNumberSyntax := regexp.MustCompile("[0-9][a-zA-Z_]|-[0-9]")
+	rng := rand.New(rand.NewSource(1))
+	// The old lexer converted each byte to a string as a rune, mangling characters outside of ASCII, and had
+	// no escape sequences, raw strings, heredocs, or list and map literals
+	alphabet := "()'\"; \n\t0123456789.abcXYZ-+*/?!<>=_~|"
+	for i := 0; i < 2000; i++ {
+		program := make([]byte, rng.Intn(30))
+		for j := range program {
+			program[j] = alphabet[rng.Intn(len(alphabet))]
+		}
+		programs = append(programs, string(program))
+	}
+	for _, program := range programs {
+		if strings.Contains(program, heredocQuotes) || newNumberSyntax.MatchString(program) {
+			continue
+		}
+		// The old lexer did not end the last comment, name or number in a program, so each ends a line
+		program += "\n"
+		err, tokens, positions, length := Lex(program, 0)
+		expectedTokens, expectedPositions, expectedLength, expectedFailed := tryLexWithTransitions(program)
+		if failed := err != nil; failed != expectedFailed {
+			t.Errorf("Expected lexing %q to fail to be %v\n", program, expectedFailed)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if length != expectedLength || !reflect.DeepEqual(tokens, expectedTokens) || !reflect.DeepEqual(positions, expectedPositions) {
+			t.Errorf("Lexing %q produced\n%v %v %d\nExpected\n%v %v %d\n", program,
+				tokens, positions, length, expectedTokens, expectedPositions, expectedLength)
+		}
+	}
+}
+
+/**
+ * Generate a program resembling a large configuration file.
+ */
+func generatedConfig(lines int) string {
+	var program strings.Builder
+	program.WriteString("(define\n")
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&program, "  (setting%d (mapping 'host' \"db%d.internal\" 'port' (+ 5432 %d) 'ratio' 0.75)) ; line %d\n", i, i, i, i)
+	}
+	program.WriteString(")\n")
+	return program.String()
+}
+
+func BenchmarkLex(b *testing.B) {
+	program := generatedConfig(1000)
+	b.SetBytes(int64(len(program)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Lex(program, 0)
+	}
+}
+
+func BenchmarkLexWithTransitions(b *testing.B) {
+	program := generatedConfig(1000)
+	b.SetBytes(int64(len(program)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lexWithTransitions(program, 0, lineStarts(program))
+	}
+}
+
+func TestLexUnicode(t *testing.T) {
+	program := "(définir 'naïve ☃' ; ünïcode comment\n  größe 名前 x́)"
+	err, lexed, positions, _ := Lex(program, 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	parseErr, forms := Parse(lexed, positions)
+	if parseErr != nil {
+		t.Fatal(parseErr.Error())
+	}
+	sexp := forms[0].(SExpression)
+	if sexp.FormName.Contained != "définir" {
+		t.Errorf("Expected the form name to be définir. Got %s\n", sexp.FormName.Contained)
+	}
+	expected := []string{"naïve ☃", "größe", "名前", "x́"}
+	for i, value := range sexp.Values {
+		text := value.(Value).String.Contained
+		if value.(Value).Type == NameT {
+			text = value.(Value).Name.Contained
+		}
+		if text != expected[i] {
+			t.Errorf("Expected value %d to be %s. Got %s\n", i, expected[i], text)
+		}
+	}
+	// Columns count characters rather than bytes
+	if pos := sexp.Values[3].(Value).Position; pos != (Position{"", 2, 12}) {
+		t.Errorf("Expected x́ to be at line 2, column 12. Got %v\n", pos)
+	}
+	// Names cannot start with a combining mark
+	if err, _, _, _ := Lex("(x ́y)", 0); err == nil {
+		t.Error("Expected to get an error lexing a name starting with a combining mark")
+	}
+}
@@ -0,0 +1,82 @@
+package interpreter
+
+import (
+	"fmt"
+)
+
+/**
+ * Limits on the resources a fig program may use, so that untrusted programs cannot hang or crash the
+ * process running them.  A limit of zero means that there is no limit.
+ * Steps count every expression evaluated, depth counts calls to functions defined in fig code that have
+ * not yet returned (tail calls replace the call they are made from), and size bounds the number of
+ * elements in any single list or map produced.
+ */
+type Limits struct {
+	MaxSteps int
+	MaxDepth int
+	MaxSize  int
+	steps    int
+	depth    int
+}
+
+/**
+ * The error produced when a program exceeds one of its limits.
+ * Unlike other errors, exceeding a limit cannot be recovered from with `try`.
+ */
+type LimitError struct {
+	Limit string
+	Max   int
+}
+
+func (e LimitError) Error() string {
+	return fmt.Sprintf("Program exceeded the maximum %s of %d.", e.Limit, e.Max)
+}
+
+/**
+ * Count one step of evaluation.  Limits may be nil, in which case nothing is limited.
+ */
+func (limits *Limits) step() error {
+	if limits == nil || limits.MaxSteps == 0 {
+		return nil
+	}
+	limits.steps++
+	if limits.steps > limits.MaxSteps {
+		return LimitError{"number of evaluation steps", limits.MaxSteps}
+	}
+	return nil
+}
+
+/**
+ * Count entering a call to a function defined in fig code.  Every successful call to enter must be matched
+ * by a call to leave once the function returns.
+ */
+func (limits *Limits) enter() error {
+	if limits == nil {
+		return nil
+	}
+	if limits.MaxDepth > 0 && limits.depth >= limits.MaxDepth {
+		return LimitError{"call depth", limits.MaxDepth}
+	}
+	limits.depth++
+	return nil
+}
+
+func (limits *Limits) leave() {
+	if limits != nil {
+		limits.depth--
+	}
+}
+
+/**
+ * Check that a value produced by a function is not larger than allowed.
+ */
+func (limits *Limits) checkSize(value Value) error {
+	if limits == nil || limits.MaxSize == 0 {
+		return nil
+	}
+	if (value.Type == ListT && len(value.List.Data) > limits.MaxSize) ||
+		(value.Type == MapT && len(value.Map.Data) > limits.MaxSize) {
+		return LimitError{"list or map size", limits.MaxSize}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+/**
+ * Escape sequences that stand for a single character.
+ */
+var simpleEscapes = map[byte]string{
+	'n':  "\n",
+	't':  "\t",
+	'r':  "\r",
+	'0':  "\x00",
+	'\\': "\\",
+	'\'': "'",
+	'"':  "\"",
+}
+
+/**
+ * Escape sequences that are followed by a number of hexadecimal digits giving the character they stand for.
+ * \x gives a single byte, while \u and \U give a unicode code point.
+ */
+var hexEscapes = map[byte]int{
+	'x': 2,
+	'u': 4,
+	'U': 8,
+}
+
+/**
+ * Decode the escape sequence starting with the backslash at an index in a string, producing the text the
+ * sequence stands for and the number of characters the sequence takes up.
+ */
+func unescape(str string, i int) (error, string, int) {
+	if i+1 >= len(str) {
+		return errors.New("Expected an escape sequence after \\."), "", 1
+	}
+	kind := str[i+1]
+	if escaped, found := simpleEscapes[kind]; found {
+		return nil, escaped, 2
+	}
+	digits, found := hexEscapes[kind]
+	if !found {
+		char, _ := utf8.DecodeRuneInString(str[i+1:])
+		return errors.New(fmt.Sprintf("Unknown escape sequence \\%c.", char)), "", 1
+	}
+	errMsg := fmt.Sprintf("The escape sequence \\%c expects %d hexadecimal digits.", kind, digits)
+	if i+2+digits > len(str) {
+		return errors.New(errMsg), "", 1
+	}
+	code, parseErr := strconv.ParseUint(str[i+2:i+2+digits], 16, 32)
+	if parseErr != nil {
+		return errors.New(errMsg), "", 1
+	}
+	if kind == 'x' {
+		return nil, string([]byte{byte(code)}), 2 + digits
+	}
+	if !utf8.ValidRune(rune(code)) {
+		errMsg = fmt.Sprintf("The escape sequence %s is not a valid unicode character.", str[i:i+2+digits])
+		return errors.New(errMsg), "", 1
+	}
+	return nil, string(rune(code)), 2 + digits
+}
+
+/**
+ * Decode every escape sequence in a string.
+ */
+func unescapeAll(str string) (error, string) {
+	var unescaped strings.Builder
+	for i := 0; i < len(str); i++ {
+		if str[i] != '\\' {
+			unescaped.WriteByte(str[i])
+			continue
+		}
+		err, decoded, length := unescape(str, i)
+		if err != nil {
+			return err, ""
+		}
+		unescaped.WriteString(decoded)
+		i += length - 1
+	}
+	return nil, unescaped.String()
+}
+
+/**
+ * Read a heredoc, a string written between triple double-quotes that may span multiple lines, starting at an
+ * index in a program.  Produces the contents of the heredoc and the index just past its end.
+ * A line break right after the opening quotes is dropped, as is the indentation common to all of the lines
+ * that are not blank, so heredocs can be indented along with the code around them.  When the closing quotes are
+ * on a line of their own, the contents end with a line break.  Escape sequences are decoded after removing
+ * indentation.
+ */
+func readHeredoc(program string, i int) (error, string, int) {
+	start := i + len(heredocQuotes)
+	for j := start; j < len(program); j++ {
+		if program[j] == '\\' {
+			j++
+		} else if strings.HasPrefix(program[j:], heredocQuotes) {
+			err, contents := unescapeAll(dedent(program[start:j]))
+			return err, contents, j + len(heredocQuotes)
+		}
+	}
+	return errors.New("Unterminated heredoc. Heredocs must be closed with " + heredocQuotes + "."), "", len(program)
+}
+
+const heredocQuotes = `"""`
+
+/**
+ * Remove the first line break and the indentation common to each line that is not blank from a heredoc.
+ */
+func dedent(text string) string {
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	text = strings.TrimPrefix(text, "\n")
+	lines := strings.Split(text, "\n")
+	indent := ""
+	found := false
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			// Blank lines, including the line with the closing quotes, do not count towards the indentation
+			lines[i] = ""
+			continue
+		}
+		lineIndent := line[:len(line)-len(trimmed)]
+		if !found {
+			indent, found = lineIndent, true
+		}
+		for !strings.HasPrefix(lineIndent, indent) {
+			indent = indent[:len(indent)-1]
+		}
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, indent)
+	}
+	return strings.Join(lines, "\n")
+}
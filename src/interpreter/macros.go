@@ -0,0 +1,265 @@
+package interpreter
+
+import (
+	"errors"
+	"sort"
+)
+
+/**
+ * Convert code into data that fig programs can compute with.
+ * S-Expressions become lists whose first element is the name of the function or special form, and
+ * names become name values rather than being looked up.  Everything else is already data.  List and map
+ * literals are kept as lists starting with [] or {}, so that macros are given exactly the code they were called
+ * with, and literals whose first element is a name are not turned into calls when the code is rebuilt.
+ */
+func CodeToData(thing interface{}) Value {
+	switch thing.(type) {
+	case SExpression:
+		sexp := thing.(SExpression)
+		list := NewList()
+		list.List.Data = append(list.List.Data, NewName(sexp.FormName.Contained))
+		for _, value := range sexp.Values {
+			list.List.Data = append(list.List.Data, CodeToData(value))
+		}
+		return list
+	case Value:
+		return thing.(Value)
+	}
+	return Value{}
+}
+
+/**
+ * Convert data back into code that can be evaluated.
+ * Lists that start with a name become S-Expressions, and other lists and maps become list and map literals, so
+ * that any names they contain are evaluated.  Every other value is left as it is.
+ */
+func DataToCode(value Value) interface{} {
+	switch value.Type {
+	case ListT:
+		if len(value.List.Data) > 0 && value.List.Data[0].Type == NameT {
+			sexp := NewSExpression(value.List.Data[0].Name.Contained)
+			for _, item := range value.List.Data[1:] {
+				sexp.Values = append(sexp.Values, DataToCode(item))
+			}
+			return sexp
+		}
+		sexp := NewSExpression(ListLiteralForm)
+		for _, item := range value.List.Data {
+			sexp.Values = append(sexp.Values, DataToCode(item))
+		}
+		return sexp
+	case MapT:
+		keys := make([]string, 0, len(value.Map.Data))
+		for key := range value.Map.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		sexp := NewSExpression(MapLiteralForm)
+		for _, key := range keys {
+			sexp.Values = append(sexp.Values, NewString(key), DataToCode(value.Map.Data[key]))
+		}
+		return sexp
+	}
+	return value
+}
+
+/**
+ * Produce the data for a quoted S-Expression from the data for the values inside of it.  Quoted list and map
+ * literals produce the list or map they are written as, with their elements left unevaluated, and other
+ * S-Expressions produce a list starting with the name of their function or special form.
+ */
+func quotedData(sexp SExpression, elements []Value) (error, Value) {
+	switch sexp.FormName.Contained {
+	case ListLiteralForm:
+		list := NewList()
+		list.List.Data = elements
+		return nil, list
+	case MapLiteralForm:
+		if len(elements)%2 == 1 {
+			return errors.New("Map literals must contain an even number of keys and values."), Value{}
+		}
+		mapping := NewMap()
+		for i := 0; i < len(elements); i += 2 {
+			name, isKey := mapKey(elements[i])
+			if !isKey {
+				return errors.New("Quoted map literal keys must be strings or keywords."), Value{}
+			}
+			if _, found := mapping.Map.Data[name]; found {
+				return errors.New("Duplicate key " + name + " in map literal."), Value{}
+			}
+			mapping.Map.Data[name] = elements[i+1]
+		}
+		return nil, mapping
+	}
+	list := NewList()
+	list.List.Data = append([]Value{NewName(sexp.FormName.Contained)}, elements...)
+	return nil, list
+}
+
+/**
+ * Convert quoted code into data.  This is like CodeToData, except that list and map literals become the lists
+ * and maps they are written as.
+ */
+func quote(thing interface{}) (error, Value) {
+	sexp, isSexp := thing.(SExpression)
+	if !isSexp {
+		return nil, CodeToData(thing)
+	}
+	elements := make([]Value, 0, len(sexp.Values))
+	for _, value := range sexp.Values {
+		err, element := quote(value)
+		if err != nil {
+			return err, Value{}
+		}
+		elements = append(elements, element)
+	}
+	return quotedData(sexp, elements)
+}
+
+/**
+ * Evaluate a `quote` form, which produces its argument as data without evaluating it.
+ */
+func EvaluateQuote(sexp SExpression, env *Environment) (error, Value, *Environment) {
+	if len(sexp.Values) != 1 {
+		return errors.New("Quote expects exactly one expression to quote."), Value{}, env
+	}
+	err, value := quote(sexp.Values[0])
+	return err, value, env
+}
+
+/**
+ * Build the data for a quasiquoted expression, evaluating only the parts wrapped in `unquote` and splicing
+ * the elements of lists wrapped in `unquote-splicing` into the enclosing list.
+ */
+func quasiquote(thing interface{}, env *Environment) (error, Value) {
+	sexp, isSexp := thing.(SExpression)
+	if !isSexp {
+		return nil, CodeToData(thing)
+	}
+	switch sexp.FormName.Contained {
+	case "unquote":
+		if len(sexp.Values) != 1 {
+			return errors.New("Unquote expects exactly one expression to evaluate."), Value{}
+		}
+		err, value, _ := Evaluate(sexp.Values[0], env)
+		return err, value
+	case "unquote-splicing":
+		return errors.New("Unquote-splicing can only be used inside of a quasiquoted S-Expression."), Value{}
+	}
+	elements := make([]Value, 0, len(sexp.Values))
+	for _, value := range sexp.Values {
+		inner, isInnerSexp := value.(SExpression)
+		if isInnerSexp && inner.FormName.Contained == "unquote-splicing" {
+			if len(inner.Values) != 1 {
+				return errors.New("Unquote-splicing expects exactly one expression to evaluate."), Value{}
+			}
+			err, spliced, _ := Evaluate(inner.Values[0], env)
+			if err != nil {
+				return err, Value{}
+			}
+			if spliced.Type != ListT {
+				return errors.New("Unquote-splicing expects its expression to evaluate to a list."), Value{}
+			}
+			elements = append(elements, spliced.List.Data...)
+			continue
+		}
+		err, quoted := quasiquote(value, env)
+		if err != nil {
+			return err, Value{}
+		}
+		elements = append(elements, quoted)
+	}
+	return quotedData(sexp, elements)
+}
+
+/**
+ * Evaluate a `quasiquote` form, which produces its argument as data like `quote` except that expressions
+ * wrapped in `unquote` or `unquote-splicing` are evaluated and inserted into the result.
+ */
+func EvaluateQuasiquote(sexp SExpression, env *Environment) (error, Value, *Environment) {
+	if len(sexp.Values) != 1 {
+		return errors.New("Quasiquote expects exactly one expression to quote."), Value{}, env
+	}
+	err, value := quasiquote(sexp.Values[0], env)
+	return err, value, env
+}
+
+/**
+ * Evaluate a `defmacro` form, which defines a macro with a name, a list of argument names and a body.
+ * The macro's body is evaluated like a function's, except that its arguments are the unevaluated code it
+ * was called with, and the data it produces is evaluated as code in place of the call.
+ */
+func EvaluateDefmacro(sexp SExpression, env *Environment) (error, Value, *Environment) {
+	if len(sexp.Values) != 3 {
+		errMsg := "Macro definitions expect a name, an S-Expression with a set of argument names and a body."
+		return errors.New(errMsg), Value{}, env
+	}
+	name, isValue := sexp.Values[0].(Value)
+	if !isValue || name.Type != NameT {
+		return errors.New("Macros must be given a name to be defined as."), Value{}, env
+	}
+	err, macro, _ := EvaluateFunction(NewSExpression("function", sexp.Values[1], sexp.Values[2]), env)
+	if err != nil {
+		return err, Value{}, env
+	}
+	macro.Function.FunctionName = name.Name
+	macro.Function.IsMacro = true
+	env.Define(name.Name.Contained, macro)
+	return nil, macro, env
+}
+
+/**
+ * Find the macro bound to a name, if there is one.
+ */
+func lookupMacro(name string, env *Environment) (Function, bool) {
+	value, found := env.Lookup(name)
+	if !found || value.Type != FunctionT || !value.Function.IsMacro {
+		return Function{}, false
+	}
+	return value.Function, true
+}
+
+/**
+ * Expand a single call to a macro by applying the macro to the unevaluated arguments in the call and
+ * converting the data it produces into code.
+ */
+func expandMacro(macro Function, sexp SExpression) (error, interface{}) {
+	arguments := make([]Value, len(sexp.Values))
+	for i, value := range sexp.Values {
+		arguments[i] = CodeToData(value)
+	}
+	expansion, err := Apply(macro, arguments...)
+	if err != nil {
+		return err, nil
+	}
+	return nil, DataToCode(expansion)
+}
+
+/**
+ * Evaluate a `macroexpand` form, which evaluates its argument to data representing code and repeatedly
+ * expands it until it is no longer a call to a macro.  This is useful for seeing what code a macro produces.
+ */
+func EvaluateMacroexpand(sexp SExpression, env *Environment) (error, Value, *Environment) {
+	if len(sexp.Values) != 1 {
+		return errors.New("Macroexpand expects exactly one expression to expand."), Value{}, env
+	}
+	err, data, _ := Evaluate(sexp.Values[0], env)
+	if err != nil {
+		return err, Value{}, env
+	}
+	code := DataToCode(data)
+	for {
+		call, isSexp := code.(SExpression)
+		if !isSexp {
+			break
+		}
+		macro, isMacro := lookupMacro(call.FormName.Contained, env)
+		if !isMacro {
+			break
+		}
+		if err, code = expandMacro(macro, call); err != nil {
+			return err, Value{}, env
+		}
+	}
+	return nil, CodeToData(code), env
+}
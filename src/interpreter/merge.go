@@ -0,0 +1,128 @@
+package interpreter
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+/**
+ * The ways that two lists can be combined when deep-merging the values containing them.
+ * Lists can be replaced by the later list, have the later list appended to them, or be treated as collections of
+ * maps identified by the value of one of their keys, in which case maps with the same identity are merged.
+ */
+type ListStrategy int
+
+const (
+	ReplaceLists    ListStrategy = iota
+	AppendLists     ListStrategy = iota
+	MergeListsByKey ListStrategy = iota
+)
+
+type MergeStrategy struct {
+	Lists ListStrategy
+	Key   string // The key identifying the maps in lists merged by key
+}
+
+// The names of list strategies, as written in programs and on the command line
+var listStrategies = map[string]ListStrategy{
+	"replace":      ReplaceLists,
+	"append":       AppendLists,
+	"merge-by-key": MergeListsByKey,
+}
+
+/**
+ * Parse a merge strategy written as `replace`, `append` or `merge-by-key:key`, where key names the map key that
+ * identifies the maps in a list.
+ */
+func ParseMergeStrategy(spec string) (MergeStrategy, error) {
+	name, key := spec, ""
+	if separator := strings.Index(spec, ":"); separator >= 0 {
+		name, key = spec[:separator], spec[separator+1:]
+	}
+	lists, found := listStrategies[name]
+	if !found {
+		errMsg := "Unknown merge strategy " + spec + ". Expected replace, append or merge-by-key:key."
+		return MergeStrategy{}, errors.New(errMsg)
+	}
+	if (lists == MergeListsByKey) != (key != "") {
+		errMsg := "Only the merge-by-key strategy takes a key, and it requires one, as in merge-by-key:name."
+		return MergeStrategy{}, errors.New(errMsg)
+	}
+	return MergeStrategy{lists, key}, nil
+}
+
+/**
+ * Merge a value into another, producing a new value.  Maps are merged key by key, recursively merging the
+ * values of keys found in both, and lists are combined according to the strategy.  In every other case,
+ * including when the two values have different types, the value being merged in replaces the other.
+ */
+func DeepMerge(base, override Value, strategy MergeStrategy) Value {
+	if base.Type == MapT && override.Type == MapT {
+		merged := NewMap()
+		for key, value := range base.Map.Data {
+			merged.Map.Data[key] = value
+		}
+		for key, value := range override.Map.Data {
+			if existing, found := merged.Map.Data[key]; found {
+				value = DeepMerge(existing, value, strategy)
+			}
+			merged.Map.Data[key] = value
+		}
+		merged.Ignored = override.Ignored
+		return merged
+	}
+	if base.Type != ListT || override.Type != ListT || strategy.Lists == ReplaceLists {
+		return override
+	}
+	merged := NewList()
+	merged.List.Data = append(merged.List.Data, base.List.Data...)
+	merged.Ignored = override.Ignored
+	for _, item := range override.List.Data {
+		index := -1
+		if strategy.Lists == MergeListsByKey {
+			index = indexByKey(merged.List.Data, item, strategy.Key)
+		}
+		if index < 0 {
+			merged.List.Data = append(merged.List.Data, item)
+		} else {
+			merged.List.Data[index] = DeepMerge(merged.List.Data[index], item, strategy)
+		}
+	}
+	return merged
+}
+
+/**
+ * Find the index of the map in a list that has the same value for a key as the given item, or -1 if the item
+ * has no such key or no map matches it.
+ */
+func indexByKey(list []Value, item Value, key string) int {
+	identity, found := item.Map.Data[key]
+	if item.Type != MapT || !found {
+		return -1
+	}
+	for i, other := range list {
+		otherIdentity, found := other.Map.Data[key]
+		if other.Type == MapT && found && otherIdentity.Type == identity.Type &&
+			reflect.DeepEqual(Unwrap(otherIdentity), Unwrap(identity)) {
+			return i
+		}
+	}
+	return -1
+}
+
+/**
+ * Merge the definitions made in a layer, such as the global scope of a later program, into this scope.
+ * Names bound in both scopes are deep-merged, with the layer's value taking precedence.
+ */
+func (env *Environment) MergeLayer(layer *Environment, strategy MergeStrategy) {
+	for name, value := range layer.Bindings {
+		if existing, found := env.Bindings[name]; found {
+			value = DeepMerge(existing, value, strategy)
+		}
+		env.Define(name, value)
+	}
+	for prefix, module := range layer.Imports {
+		env.Import(prefix, module)
+	}
+}
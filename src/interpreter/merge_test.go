@@ -0,0 +1,113 @@
+package interpreter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMergeStrategy(t *testing.T) {
+	tests := []struct {
+		Spec     string
+		Strategy MergeStrategy
+	}{
+		{"replace", MergeStrategy{ReplaceLists, ""}},
+		{"append", MergeStrategy{AppendLists, ""}},
+		{"merge-by-key:name", MergeStrategy{MergeListsByKey, "name"}},
+	}
+	for _, test := range tests {
+		strategy, err := ParseMergeStrategy(test.Spec)
+		if err != nil {
+			t.Error(err.Error())
+		}
+		if strategy != test.Strategy {
+			t.Errorf("Expected %q to parse to %v. Got %v\n", test.Spec, test.Strategy, strategy)
+		}
+	}
+	for _, spec := range []string{"", "prepend", "merge-by-key", "merge-by-key:", "append:name"} {
+		if _, err := ParseMergeStrategy(spec); err == nil {
+			t.Errorf("Expected an error parsing the merge strategy %q\n", spec)
+		}
+	}
+}
+
+/**
+ * Build a value from Go data, for comparing the results of merges.
+ */
+func wrapped(t *testing.T, thing interface{}) Value {
+	value, err := Wrap(thing)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return value
+}
+
+func TestDeepMerge(t *testing.T) {
+	base := wrapped(t, map[string]interface{}{
+		"host":  "localhost",
+		"port":  int64(5432),
+		"tags":  []interface{}{"a"},
+		"users": []interface{}{map[string]interface{}{"name": "admin", "role": "rw"}},
+		"tls":   map[string]interface{}{"enabled": false, "cert": "dev.pem"},
+	})
+	override := wrapped(t, map[string]interface{}{
+		"host":  "prod.internal",
+		"tags":  []interface{}{"b"},
+		"users": []interface{}{map[string]interface{}{"name": "admin", "role": "ro"}, map[string]interface{}{"name": "ci"}},
+		"tls":   map[string]interface{}{"enabled": true},
+	})
+	tests := []struct {
+		Strategy MergeStrategy
+		Tags     []interface{}
+		Users    []interface{}
+	}{
+		{MergeStrategy{ReplaceLists, ""}, []interface{}{"b"},
+			[]interface{}{map[string]interface{}{"name": "admin", "role": "ro"}, map[string]interface{}{"name": "ci"}}},
+		{MergeStrategy{AppendLists, ""}, []interface{}{"a", "b"},
+			[]interface{}{map[string]interface{}{"name": "admin", "role": "rw"},
+				map[string]interface{}{"name": "admin", "role": "ro"}, map[string]interface{}{"name": "ci"}}},
+		{MergeStrategy{MergeListsByKey, "name"}, []interface{}{"a", "b"},
+			[]interface{}{map[string]interface{}{"name": "admin", "role": "ro"}, map[string]interface{}{"name": "ci"}}},
+	}
+	for _, test := range tests {
+		merged := Unwrap(DeepMerge(base, override, test.Strategy)).(map[string]interface{})
+		expected := map[string]interface{}{
+			"host":  "prod.internal",
+			"port":  int64(5432),
+			"tags":  test.Tags,
+			"users": test.Users,
+			"tls":   map[string]interface{}{"enabled": true, "cert": "dev.pem"},
+		}
+		if !reflect.DeepEqual(merged, expected) {
+			t.Errorf("Merging with %v produced\n%v\nExpected\n%v\n", test.Strategy, merged, expected)
+		}
+	}
+	// Merging does not modify either of the values merged
+	if len(base.Map.Data["tls"].Map.Data) != 2 || len(override.Map.Data["tls"].Map.Data) != 1 {
+		t.Error("Expected deep merging to leave the merged maps unchanged")
+	}
+	// Values of different types are replaced rather than merged
+	replaced := DeepMerge(base, NewString("none"), MergeStrategy{})
+	if replaced.Type != StringT {
+		t.Errorf("Expected merging a string into a map to produce the string. Got %v\n", Unwrap(replaced))
+	}
+}
+
+func TestMergeLayer(t *testing.T) {
+	env := NewEnvironment(nil)
+	env.Define("db", wrapped(t, map[string]interface{}{"host": "localhost", "port": int64(5432)}))
+	env.Define("name", NewString("app"))
+	layer := NewEnvironment(env)
+	layer.Define("db", wrapped(t, map[string]interface{}{"host": "prod.internal"}))
+	layer.Define("region", NewString("eu"))
+	env.MergeLayer(layer, MergeStrategy{})
+	expected := map[string]interface{}{"host": "prod.internal", "port": int64(5432)}
+	if db, _ := env.Lookup("db"); !reflect.DeepEqual(Unwrap(db), expected) {
+		t.Errorf("Expected db to be deep-merged. Got %v\n", Unwrap(db))
+	}
+	if name, _ := env.Lookup("name"); name.String.Contained != "app" {
+		t.Errorf("Expected names the layer does not define to be kept. Got %v\n", Unwrap(name))
+	}
+	if region, _ := env.Lookup("region"); region.String.Contained != "eu" {
+		t.Errorf("Expected names only the layer defines to be added. Got %v\n", Unwrap(region))
+	}
+}
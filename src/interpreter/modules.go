@@ -0,0 +1,181 @@
+package interpreter
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/**
+ * A loader finds, evaluates and caches the fig files imported by programs.
+ * Each file is evaluated only once, in its own global scope, no matter how many times it is imported.
+ * Imported files are searched for relative to the file importing them first, and then in each of the
+ * search paths in order.  Imported files are subject to the same limits as the programs importing them.
+ */
+type Loader struct {
+	SearchPaths []string
+	Limits      *Limits
+	prelude     *Environment
+	modules     map[string]*Environment
+	loading     []string
+}
+
+/**
+ * Create a loader whose modules all start out with the values in the prelude, such as the standard library.
+ */
+func NewLoader(prelude map[string]Value, searchPaths ...string) *Loader {
+	preludeEnv := NewEnvironment(nil)
+	for name, value := range prelude {
+		preludeEnv.Define(name, value)
+	}
+	return &Loader{searchPaths, nil, preludeEnv, map[string]*Environment{}, []string{}}
+}
+
+/**
+ * Find the file referred to by an import, relative to the file containing the import.
+ */
+func (loader *Loader) Resolve(path, fromFile string) (string, error) {
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+	candidates := []string{filepath.Join(filepath.Dir(fromFile), path)}
+	for _, searchPath := range loader.SearchPaths {
+		candidates = append(candidates, filepath.Join(searchPath, path))
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return filepath.Abs(candidate)
+		}
+	}
+	return "", errors.New("Could not find " + path + " to import. Searched in " + strings.Join(candidates, ", "))
+}
+
+/**
+ * Load a fig file, producing the global scope that results from evaluating it.
+ * Files that have already been loaded are not evaluated again.
+ */
+func (loader *Loader) Load(path string) (error, *Environment) {
+	if module, found := loader.modules[path]; found {
+		return nil, module
+	}
+	for i, loading := range loader.loading {
+		if loading == path {
+			cycle := append(append([]string{}, loader.loading[i:]...), path)
+			return errors.New("Import cycle detected: " + strings.Join(cycle, " -> ")), nil
+		}
+	}
+	programBytes, readErr := ioutil.ReadFile(path)
+	if readErr != nil {
+		return readErr, nil
+	}
+	loader.loading = append(loader.loading, path)
+	defer func() {
+		loader.loading = loader.loading[:len(loader.loading)-1]
+	}()
+	module := NewEnvironment(loader.prelude)
+	module.File = path
+	module.Loader = loader
+	module.Limits = loader.Limits
+	if err := EvaluateProgram(string(programBytes), module); err != nil {
+		// Errors with a position already name the file they occurred in
+		if _, isPosErr := err.(PositionError); isPosErr {
+			return err, nil
+		}
+		return errors.New("In " + path + ": " + err.Error()), nil
+	}
+	loader.modules[path] = module
+	return nil, module
+}
+
+/**
+ * Evaluate the program in the file a run starts from, which is not loaded as a module itself.  The file is
+ * recorded as being loaded while it is evaluated, so that a file importing it, directly or through other imports,
+ * is reported as an import cycle rather than evaluating the program a second time.
+ */
+func (loader *Loader) EvaluateEntry(program string, env *Environment) error {
+	path, absErr := filepath.Abs(env.File)
+	if absErr != nil {
+		return absErr
+	}
+	loader.loading = append(loader.loading, path)
+	defer func() {
+		loader.loading = loader.loading[:len(loader.loading)-1]
+	}()
+	return EvaluateProgram(program, env)
+}
+
+/**
+ * Evaluate an `import` form, which loads another fig file and makes its definitions available under a prefix,
+ * so that a definition named `account` in an imported file `db.fig` can be referred to as `db/account`.
+ * The prefix defaults to the name of the file without its extension, and can be chosen by providing a name
+ * after the path to import.
+ */
+func EvaluateImport(sexp SExpression, env *Environment) (error, Value, *Environment) {
+	if len(sexp.Values) != 1 && len(sexp.Values) != 2 {
+		return errors.New("Import expects the path of a file to import and optionally a prefix name."), Value{}, env
+	}
+	path, isValue := sexp.Values[0].(Value)
+	if !isValue || path.Type != StringT {
+		return errors.New("Import expects the path of the file to import to be a string."), Value{}, env
+	}
+	prefix := strings.TrimSuffix(filepath.Base(path.String.Contained), filepath.Ext(path.String.Contained))
+	if len(sexp.Values) == 2 {
+		name, isName := sexp.Values[1].(Value)
+		if !isName || name.Type != NameT {
+			return errors.New("Import expects the prefix for imported definitions to be a name."), Value{}, env
+		}
+		prefix = name.Name.Contained
+	}
+	if env.Loader == nil {
+		return errors.New("Cannot import " + path.String.Contained + " without a loader."), Value{}, env
+	}
+	resolved, resolveErr := env.Loader.Resolve(path.String.Contained, env.File)
+	if resolveErr != nil {
+		return resolveErr, Value{}, env
+	}
+	loadErr, module := env.Loader.Load(resolved)
+	if loadErr != nil {
+		return loadErr, Value{}, env
+	}
+	env.Import(prefix, module)
+	return nil, Value{}, env
+}
+
+/**
+ * Lex, parse and evaluate every form in a fig program in the given environment.
+ * Errors report the position in the program at which they occurred, along with the line of code containing it.
+ */
+func EvaluateProgram(program string, env *Environment) error {
+	err := evaluateProgram(program, env)
+	return withExcerpt(err, program, env.File)
+}
+
+func evaluateProgram(program string, env *Environment) error {
+	lexErr, lexed, positions, length := Lex(program, 0)
+	if lexErr != nil {
+		pos := lexErr.(LexError).Position
+		pos.File = env.File
+		return atPosition(lexErr, pos)
+	}
+	if length != len(program) {
+		// Lexing stops at the unmatched parenthesis, so it is the last token
+		pos := positions[len(positions)-1]
+		pos.File = env.File
+		return atPosition(errors.New(unmatchedMsg), pos)
+	}
+	for i := range positions {
+		positions[i].File = env.File
+	}
+	parseErr, parsedForms := Parse(lexed, positions)
+	if parseErr != nil {
+		return parseErr
+	}
+	for _, form := range parsedForms {
+		if err, _, _ := Evaluate(form, env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
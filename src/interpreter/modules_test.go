@@ -0,0 +1,99 @@
+package interpreter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"lib/db.fig":   "(import \"util.fig\") (define (host \"db.internal\") (url (util/scheme host)))",
+		"lib/util.fig": "(define (scheme (function (h) h)))",
+		"shared/a.fig": "(import \"b.fig\")",
+		"shared/b.fig": "(import \"a.fig\")",
+		"main.fig":     "",
+	}
+	for name, program := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(program), 0644); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	loader := NewLoader(map[string]Value{}, filepath.Join(dir, "shared"))
+	env := NewEnvironment(nil)
+	env.File = filepath.Join(dir, "main.fig")
+	env.Loader = loader
+	// Definitions are available under the name of the file and under a chosen prefix
+	err1 := EvaluateProgram("(import \"lib/db.fig\") (import \"lib/db.fig\" database)", env)
+	if err1 != nil {
+		t.Fatal(err1.Error())
+	}
+	err2, value2, _ := Evaluate(NewName("db/host"), env)
+	if err2 != nil {
+		t.Error(err2.Error())
+	}
+	if value2.String.Contained != "db.internal" {
+		t.Errorf("Expected db/host to be db.internal. Got %s\n", value2.String.Contained)
+	}
+	err3, value3, _ := Evaluate(NewName("database/url"), env)
+	if err3 != nil {
+		t.Error(err3.Error())
+	}
+	if value3.String.Contained != "db.internal" {
+		t.Errorf("Expected database/url to be db.internal. Got %s\n", value3.String.Contained)
+	}
+	// Importing does not add any definitions to the importing scope
+	if len(env.Bindings) != 0 {
+		t.Errorf("Expected imported definitions not to be copied into the global scope. Got %d\n", len(env.Bindings))
+	}
+	// Each file is only evaluated once
+	if len(loader.modules) != 2 {
+		t.Errorf("Expected two modules to be loaded. Got %d\n", len(loader.modules))
+	}
+	if env.Imports["db"] != env.Imports["database"] {
+		t.Error("Expected a file imported twice to be loaded only once")
+	}
+	// Files are found in the search paths, and cycles are detected
+	err4 := EvaluateProgram("(import \"a.fig\")", env)
+	if err4 == nil {
+		t.Error("Expected to get an error importing files that import one another")
+	}
+	err5 := EvaluateProgram("(import \"missing.fig\")", env)
+	if err5 == nil {
+		t.Error("Expected to get an error importing a file that does not exist")
+	}
+}
+
+func TestImportEntry(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.fig":   "(import \"config.fig\") (define (name \"main\"))",
+		"config.fig": "(import \"main.fig\")",
+		"other.fig":  "(import \"lib.fig\")",
+		"lib.fig":    "(import \"other.fig\")",
+	}
+	for name, program := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(program), 0644); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	// A file importing the file a run starts from is a cycle, rather than evaluating the program again
+	for _, entry := range []string{"main.fig", "other.fig"} {
+		loader := NewLoader(map[string]Value{})
+		env := NewEnvironment(nil)
+		env.File = filepath.Join(dir, entry)
+		env.Loader = loader
+		err := loader.EvaluateEntry(files[entry], env)
+		if err == nil || !strings.Contains(err.Error(), "Import cycle detected: "+env.File) {
+			t.Errorf("Expected an import cycle starting at %s. Got %v\n", entry, err)
+		}
+		if len(loader.loading) != 0 {
+			t.Errorf("Expected no files to still be loading after evaluating %s. Got %v\n", entry, loader.loading)
+		}
+	}
+}
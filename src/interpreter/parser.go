@@ -6,7 +6,7 @@ import (
 	"strings"
 )
 
-type SimpleParser func([]Token, int) (error, Value, int)
+type SimpleParser func([]Token, []Position, int) (error, Value, int)
 
 var SimpleParsersTable = map[Token]SimpleParser{
 	START_STRING: ParseString,
@@ -14,101 +14,201 @@ var SimpleParsersTable = map[Token]SimpleParser{
 	START_NAME:   ParseName,
 }
 
+const (
+	unclosedMsg  = "Unclosed S-Expression encountered. Check that all openning parentheses are closed properly."
+	unmatchedMsg = "Found a closing parenthesis without a matching opening parenthesis."
+)
+
+// Descriptions of what each closing token closes, for error messages
+var closed = map[Token]string{
+	END_SEXP: "S-Expression",
+	END_LIST: "list",
+	END_MAP:  "map",
+}
+
+/**
+ * Determine if a token marks the start or end of something, as opposed to being a character of it.
+ */
+func isMarker(token Token) bool {
+	return len(token) > 2 && token[0] == '[' && token[len(token)-1] == ']'
+}
+
+/**
+ * Find the position of the token at an index.  Tokens that were not produced by lexing a program, such as those
+ * constructed directly in tests, have no known position.
+ */
+func tokenPosition(positions []Position, i int) Position {
+	if i < 0 || i >= len(positions) {
+		return Position{}
+	}
+	return positions[i]
+}
+
+/**
+ * Create an error describing a problem with the token at an index, including the token's position if it is known.
+ */
+func parseError(errMsg string, positions []Position, i int) error {
+	return atPosition(errors.New(errMsg), tokenPosition(positions, i))
+}
+
+/**
+ * Create an error for a name, number, string or comment whose tokens run out before the token that ends it,
+ * which happens when a program ends partway through one.  The error is reported at the token that started it.
+ */
+func unclosedError(what string, positions []Position, start int) error {
+	return parseError("Unclosed "+what+" encountered.", positions, start)
+}
+
 /**
- * Parse a name that refers to a value.
+ * Parse a name that refers to a value, or a keyword such as :host, which is written like a name starting with
+ * a colon but evaluates to itself.
  */
-func ParseName(tokens []Token, i int) (error, Value, int) {
+func ParseName(tokens []Token, positions []Position, i int) (error, Value, int) {
 	value := Value{}
 	value.Type = UnassignedT
 	if tokens[i] != START_NAME {
 		errMsg := "Expected START_NAME, got " + string(tokens[i])
-		return errors.New(errMsg), value, i
+		return parseError(errMsg, positions, i), value, i
 	}
 	name := Name{""}
+	start := i
 	i++
-	for tokens[i] != END_NAME {
-		if len(tokens[i]) > 1 {
+	for i < len(tokens) && tokens[i] != END_NAME {
+		if isMarker(tokens[i]) {
 			errMsg := "Expected token or END_NAME. Found " + string(tokens[i])
-			return errors.New(errMsg), value, i
+			return parseError(errMsg, positions, i), value, i
 		}
 		name.Contained += string(tokens[i])
 		i++
 	}
-	value.Type = NameT
-	value.Name = name
+	if i >= len(tokens) {
+		return unclosedError("name", positions, start), value, i
+	}
+	if len(name.Contained) > 1 && name.Contained[0] == ':' {
+		value = NewKeyword(name.Contained[1:])
+	} else {
+		value.Type = NameT
+		value.Name = name
+	}
+	value.Position = tokenPosition(positions, start)
 	return nil, value, i + 1
 }
 
 /**
  * Parse a number such as an integer or a floating point number.
  */
-func ParseNumber(tokens []Token, i int) (error, Value, int) {
+func ParseNumber(tokens []Token, positions []Position, i int) (error, Value, int) {
 	value := Value{}
 	value.Type = UnassignedT
 	if tokens[i] != START_NUMBER {
 		errMsg := "Expected START_NUMBER, got " + string(tokens[i])
-		return errors.New(errMsg), value, i
+		return parseError(errMsg, positions, i), value, i
 	}
 	numberStr := ""
+	start := i
 	i++
-	for tokens[i] != END_NUMBER {
-		if len(tokens[i]) > 1 {
+	for i < len(tokens) && tokens[i] != END_NUMBER {
+		if isMarker(tokens[i]) {
 			errMsg := "Expected token or END_NUMBER. Found " + string(tokens[i])
-			return errors.New(errMsg), value, i
+			return parseError(errMsg, positions, i), value, i
 		}
 		numberStr += string(tokens[i])
 		i++
 	}
-	if strings.Contains(numberStr, ".") {
-		value.Type = FloatT
-		f, _ := strconv.ParseFloat(numberStr, 64)
-		value.Float = FloatLiteral{f}
-	} else {
-		value.Type = IntegerT
-		i, _ := strconv.ParseInt(numberStr, 10, 64)
-		value.Integer = IntegerLiteral{i}
+	if i >= len(tokens) {
+		return unclosedError("number", positions, start), value, i
 	}
+	number, err := parseNumberLiteral(numberStr)
+	if err != nil {
+		return parseError(err.Error(), positions, start), value, i
+	}
+	value = number
+	value.Position = tokenPosition(positions, start)
 	return nil, value, i + 1
 }
 
+/**
+ * Parse the text of a number literal.  Integers may be written in decimal or, with a prefix of 0x, 0o or 0b,
+ * in hexadecimal, octal or binary.  Numbers containing a decimal point or an exponent, such as 1.5e-3, are
+ * floats.  Any number may be negative, and digits may be separated by underscores as in 1_000_000.
+ */
+func parseNumberLiteral(literal string) (Value, error) {
+	invalid := errors.New("Invalid number " + literal + ".")
+	digits := strings.TrimPrefix(literal, "-")
+	if len(digits) > 1 && digits[0] == '0' && strings.IndexByte("xXoObB", digits[1]) >= 0 {
+		n, err := strconv.ParseInt(literal, 0, 64)
+		if err != nil {
+			return Value{}, invalid
+		}
+		return NewInteger(n), nil
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] == '_' && (i == 0 || i == len(digits)-1 || !isDigit(digits[i-1]) || !isDigit(digits[i+1])) {
+			return Value{}, invalid
+		}
+	}
+	plain := strings.Replace(literal, "_", "", -1)
+	if strings.ContainsAny(plain, ".eE") {
+		f, err := strconv.ParseFloat(plain, 64)
+		if err != nil {
+			return Value{}, invalid
+		}
+		return NewFloat(f), nil
+	}
+	n, err := strconv.ParseInt(plain, 10, 64)
+	if err != nil {
+		return Value{}, invalid
+	}
+	return NewInteger(n), nil
+}
+
 /**
  * Parse a comment by basically just ignoring it and returning an unsassigned value.
  */
-func ParseComment(tokens []Token, i int) (error, Value, int) {
+func ParseComment(tokens []Token, positions []Position, i int) (error, Value, int) {
 	value := Value{}
 	value.Type = UnassignedT
 	if tokens[i] != START_COMMENT {
 		errMsg := "Expected START_COMMENT, got " + string(tokens[i])
-		return errors.New(errMsg), value, i
+		return parseError(errMsg, positions, i), value, i
 	}
-	for tokens[i] != END_COMMENT {
+	start := i
+	for i < len(tokens) && tokens[i] != END_COMMENT {
 		i++
 	}
+	if i >= len(tokens) {
+		return unclosedError("comment", positions, start), value, i
+	}
 	return nil, value, i + 1
 }
 
 /**
  * Parse a string. The lexer has already handled double vs single quoted strings for us.
  */
-func ParseString(tokens []Token, i int) (error, Value, int) {
+func ParseString(tokens []Token, positions []Position, i int) (error, Value, int) {
 	value := Value{}
 	value.Type = UnassignedT
 	if tokens[i] != START_STRING {
 		errMsg := "Expected START_STRING, got " + string(tokens[i])
-		return errors.New(errMsg), value, i
+		return parseError(errMsg, positions, i), value, i
 	}
 	str := ""
+	start := i
 	i++
-	for tokens[i] != END_STRING {
-		if len(tokens[i]) > 1 {
+	for i < len(tokens) && tokens[i] != END_STRING {
+		if isMarker(tokens[i]) {
 			errMsg := "Expected token or END_STRING. Found " + string(tokens[i])
-			return errors.New(errMsg), value, i
+			return parseError(errMsg, positions, i), value, i
 		}
 		str += string(tokens[i])
 		i++
 	}
+	if i >= len(tokens) {
+		return unclosedError("string", positions, start), value, i
+	}
 	value.Type = StringT
 	value.String = StringLiteral{str}
+	value.Position = tokenPosition(positions, start)
 	return nil, value, i + 1
 }
 
@@ -116,50 +216,109 @@ func ParseString(tokens []Token, i int) (error, Value, int) {
  * Parse an S-Expression, which expects to start with a name for a special form or a Function
  * and then contain some number of expressions, which may themselves be S-Expressions.
  */
-func ParseSExpression(tokens []Token, i int) (error, SExpression, int) {
+func ParseSExpression(tokens []Token, positions []Position, i int) (error, SExpression, int) {
 	sexp := SExpression{}
 	sexp.Type = SExpressionT
 	if tokens[i] != START_SEXP {
 		errMsg := "Expected START_SEXP, got " + string(tokens[i])
-		return errors.New(errMsg), sexp, i
+		return parseError(errMsg, positions, i), sexp, i
 	}
+	open := i
+	sexp.Position = tokenPosition(positions, open)
 	i++
-	formErr, formName, newStart := ParseName(tokens, i)
+	if i >= len(tokens) {
+		return parseError(unclosedMsg, positions, open), sexp, i
+	}
+	formErr, formName, newStart := ParseName(tokens, positions, i)
 	if formErr != nil {
 		return formErr, sexp, i
 	}
+	if formName.Type != NameT {
+		errMsg := "Expected the name of a function or special form, got the keyword " + formName.Keyword.String()
+		return parseError(errMsg, positions, i), sexp, i
+	}
 	sexp.FormName = formName.Name
-	i = newStart
-	if i >= len(tokens) {
-		return errors.New("Unclosed S-Expression encountered. Check that all openning parentheses are closed properly."), sexp, i
-	}
-	for tokens[i] != END_SEXP {
-		simpleParser, found := SimpleParsersTable[tokens[i]]
-		var parseErr error = nil
-		if found {
-			err, value, nextIndex := simpleParser(tokens, i)
-			sexp.Values = append(sexp.Values, value)
-			parseErr = err
-			i = nextIndex - 1 // We'll increment i at the end of the loop
-		} else if tokens[i] == START_COMMENT {
-			err, _, nextIndex := ParseComment(tokens, i)
-			parseErr = err
-			i = nextIndex - 1
-		} else {
-			err, innerSexp, nextIndex := ParseSExpression(tokens, i)
-			parseErr = err
-			i = nextIndex - 1
-			sexp.Values = append(sexp.Values, innerSexp)
+	err, values, nextIndex := parseElements(tokens, positions, newStart, open, END_SEXP)
+	sexp.Values = values
+	return err, sexp, nextIndex
+}
+
+/**
+ * Parse a list literal such as [a b c] or a map literal such as {"key" value}.  Both are represented as
+ * S-Expressions whose form name is a special form that cannot be written as a name, so that their elements
+ * are evaluated like the arguments to a function.
+ */
+func ParseCollection(tokens []Token, positions []Position, i int) (error, SExpression, int) {
+	sexp := SExpression{}
+	sexp.Type = SExpressionT
+	var end Token
+	switch tokens[i] {
+	case START_LIST:
+		sexp.FormName = Name{ListLiteralForm}
+		end = END_LIST
+	case START_MAP:
+		sexp.FormName = Name{MapLiteralForm}
+		end = END_MAP
+	default:
+		errMsg := "Expected START_LIST or START_MAP, got " + string(tokens[i])
+		return parseError(errMsg, positions, i), sexp, i
+	}
+	sexp.Position = tokenPosition(positions, i)
+	err, values, nextIndex := parseElements(tokens, positions, i+1, i, end)
+	sexp.Values = values
+	return err, sexp, nextIndex
+}
+
+// The characters that close S-Expressions, lists and maps, for error messages
+var closers = map[Token]string{
+	END_SEXP: ")",
+	END_LIST: "]",
+	END_MAP:  "}",
+}
+
+/**
+ * Parse the expressions contained in an S-Expression, list or map, which are ended by the given token.
+ * The index of the token that opened the expression is used to report it if it is never closed.
+ */
+func parseElements(tokens []Token, positions []Position, i, open int, end Token) (error, []interface{}, int) {
+	values := []interface{}{}
+	for {
+		if i >= len(tokens) {
+			errMsg := "Unclosed " + closed[end] + " encountered. Check that each " + closers[end] + " is matched."
+			if end == END_SEXP {
+				errMsg = unclosedMsg
+			}
+			return parseError(errMsg, positions, open), values, i
 		}
-		if parseErr != nil {
-			return parseErr, sexp, i
+		if tokens[i] == end {
+			return nil, values, i + 1
 		}
-		i++
-		if i >= len(tokens) {
-			return errors.New("Unclosed S-Expression encountered. Check that all openning parentheses are closed properly."), sexp, i
+		var err error
+		var element interface{}
+		switch tokens[i] {
+		case START_STRING, START_NUMBER, START_NAME:
+			err, element, i = SimpleParsersTable[tokens[i]](tokens, positions, i)
+		case START_COMMENT:
+			err, _, i = ParseComment(tokens, positions, i)
+			element = nil
+		case START_SEXP:
+			err, element, i = ParseSExpression(tokens, positions, i)
+		case START_LIST, START_MAP:
+			err, element, i = ParseCollection(tokens, positions, i)
+		case END_SEXP, END_LIST, END_MAP:
+			errMsg := "Expected " + closers[end] + " but found " + closers[tokens[i]] + "."
+			return parseError(errMsg, positions, i), values, i
+		default:
+			errMsg := "No parser available to parse token " + string(tokens[i])
+			return parseError(errMsg, positions, i), values, i
+		}
+		if err != nil {
+			return err, values, i
+		}
+		if element != nil {
+			values = append(values, element)
 		}
 	}
-	return nil, sexp, i + 1
 }
 
 /**
@@ -167,7 +326,7 @@ func ParseSExpression(tokens []Token, i int) (error, SExpression, int) {
  * type parser to invoke for each START token encountered.
  * At the end, we get a list of "stuff" which are either S-Expressions or values.
  */
-func Parse(tokens []Token) (error, []interface{}) {
+func Parse(tokens []Token, positions []Position) (error, []interface{}) {
 	parsedForms := make([]interface{}, 0)
 	for index := 0; index < len(tokens); {
 		var err error
@@ -175,18 +334,25 @@ func Parse(tokens []Token) (error, []interface{}) {
 		var nextIndex int
 		switch tokens[index] {
 		case START_SEXP:
-			err, parsed, nextIndex = ParseSExpression(tokens, index)
+			err, parsed, nextIndex = ParseSExpression(tokens, positions, index)
 		case START_COMMENT:
-			err, parsed, nextIndex = ParseComment(tokens, index)
+			err, parsed, nextIndex = ParseComment(tokens, positions, index)
 		case START_NAME:
-			err, parsed, nextIndex = ParseName(tokens, index)
+			err, parsed, nextIndex = ParseName(tokens, positions, index)
 		case START_STRING:
-			err, parsed, nextIndex = ParseString(tokens, index)
+			err, parsed, nextIndex = ParseString(tokens, positions, index)
 		case START_NUMBER:
-			err, parsed, nextIndex = ParseNumber(tokens, index)
+			err, parsed, nextIndex = ParseNumber(tokens, positions, index)
+		case START_LIST, START_MAP:
+			err, parsed, nextIndex = ParseCollection(tokens, positions, index)
+		case END_SEXP:
+			return parseError(unmatchedMsg, positions, index), parsedForms
+		case END_LIST, END_MAP:
+			errMsg := "Found a closing " + closers[tokens[index]] + " without a matching opening bracket."
+			return parseError(errMsg, positions, index), parsedForms
 		default:
 			errMsg := "No parser available to parse token " + string(tokens[index])
-			return errors.New(errMsg), parsedForms
+			return parseError(errMsg, positions, index), parsedForms
 		}
 		if err != nil {
 			return err, parsedForms
@@ -1,12 +1,13 @@
 package interpreter
 
 import (
+	"strings"
 	"testing"
 )
 
 func TestParseName(t *testing.T) {
 	tokens := []Token{START_NAME, "t", "e", "s", "t", END_NAME}
-	err, value, newStart := ParseName(tokens, 0)
+	err, value, newStart := ParseName(tokens, nil, 0)
 	if err != nil {
 		t.Error(err.Error())
 	}
@@ -20,7 +21,7 @@ func TestParseName(t *testing.T) {
 		t.Errorf("Expected name to contain 'test'. Got %s\n", value.Name.Contained)
 	}
 	etokens := []Token{START_NAME, "t", "e", "s", "t", END_NUMBER, END_NAME}
-	err, value, newStart = ParseName(etokens, 0)
+	err, value, newStart = ParseName(etokens, nil, 0)
 	if err == nil {
 		t.Error("Expected to get an error parsing an invalid name")
 	}
@@ -29,11 +30,31 @@ func TestParseName(t *testing.T) {
 	}
 }
 
+func TestParseKeyword(t *testing.T) {
+	tokens := []Token{START_NAME, ":", "h", "o", "s", "t", END_NAME}
+	err, value, _ := ParseName(tokens, nil, 0)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	if value.Type != KeywordT || value.Keyword.Contained != "host" {
+		t.Errorf("Expected to parse the keyword :host. Got %v\n", value)
+	}
+	// A colon on its own is still a name
+	err, value, _ = ParseName([]Token{START_NAME, ":", END_NAME}, nil, 0)
+	if err != nil || value.Type != NameT {
+		t.Errorf("Expected to parse : as a name. Got %v\n", value)
+	}
+	etokens := []Token{START_SEXP, START_NAME, ":", "x", END_NAME, END_SEXP}
+	if err, _, _ := ParseSExpression(etokens, nil, 0); err == nil {
+		t.Error("Expected to get an error parsing an S-Expression starting with a keyword")
+	}
+}
+
 func TestParseNumber(t *testing.T) {
 	fTokens := []Token{START_NUMBER, "3", ".", "1", "4", END_NUMBER}
 	iTokens := []Token{START_NUMBER, "3", "2", "1", END_NUMBER}
 	eTokens := []Token{START_NUMBER, "3", "2", "1", END_SEXP, END_NUMBER}
-	err, value, newStart := ParseNumber(fTokens, 0)
+	err, value, newStart := ParseNumber(fTokens, nil, 0)
 	if err != nil {
 		t.Error(err.Error())
 	}
@@ -46,7 +67,7 @@ func TestParseNumber(t *testing.T) {
 	if value.Float.Contained != 3.14 {
 		t.Errorf("Expected float to contain 3.14. Got %f\n", value.Float.Contained)
 	}
-	err, value, newStart = ParseNumber(iTokens, 0)
+	err, value, newStart = ParseNumber(iTokens, nil, 0)
 	if err != nil {
 		t.Error(err.Error())
 	}
@@ -59,7 +80,7 @@ func TestParseNumber(t *testing.T) {
 	if value.Integer.Contained != 321 {
 		t.Errorf("Expected float to contain 321. Got %d\n", value.Integer.Contained)
 	}
-	err, value, newStart = ParseNumber(eTokens, 0)
+	err, value, newStart = ParseNumber(eTokens, nil, 0)
 	if err == nil {
 		t.Error("Expected to get an error parsing an invalid number")
 	}
@@ -70,7 +91,7 @@ func TestParseNumber(t *testing.T) {
 
 func TestParseComment(t *testing.T) {
 	tokens := []Token{START_COMMENT, END_COMMENT} // The only way it appears
-	err, value, newStart := ParseComment(tokens, 0)
+	err, value, newStart := ParseComment(tokens, nil, 0)
 	if err != nil {
 		t.Error(err.Error())
 	}
@@ -84,7 +105,7 @@ func TestParseComment(t *testing.T) {
 
 func TestParseString(t *testing.T) {
 	tokens := []Token{START_STRING, "t", "e", "s", "t", END_STRING}
-	err, value, newStart := ParseString(tokens, 0)
+	err, value, newStart := ParseString(tokens, nil, 0)
 	if err != nil {
 		t.Error(err.Error())
 	}
@@ -98,7 +119,7 @@ func TestParseString(t *testing.T) {
 		t.Errorf("Expected to parse the string 'test'. Got %s\n", value.String.Contained)
 	}
 	etokens := []Token{START_STRING, "h", "i", END_NUMBER, END_STRING}
-	err, value, newStart = ParseString(etokens, 0)
+	err, value, newStart = ParseString(etokens, nil, 0)
 	if err == nil {
 		t.Error("Expected to get an error parsing a malformed string.")
 	}
@@ -109,7 +130,7 @@ func TestParseString(t *testing.T) {
 
 func TestParseSExpression(t *testing.T) {
 	tokens := []Token{START_SEXP, START_NAME, "s", "q", END_NAME, START_NUMBER, "3", END_NUMBER, END_SEXP}
-	err, sexp, newStart := ParseSExpression(tokens, 0)
+	err, sexp, newStart := ParseSExpression(tokens, nil, 0)
 	if err != nil {
 		t.Error(err.Error())
 	}
@@ -130,7 +151,7 @@ func TestParseSExpression(t *testing.T) {
 		t.Errorf("Expected first value in sexp to be 3. Got %d\n", first.Integer.Contained)
 	}
 	tokens2 := []Token{START_SEXP, START_NAME, "a", END_NAME, START_SEXP, START_NAME, "s", "q", END_NAME, START_NUMBER, "3", END_NUMBER, END_SEXP, END_SEXP}
-	err, sexp, newStart = ParseSExpression(tokens2, 0)
+	err, sexp, newStart = ParseSExpression(tokens2, nil, 0)
 	if err != nil {
 		t.Error(err.Error())
 	}
@@ -149,7 +170,7 @@ func TestParseSExpression(t *testing.T) {
 		t.Errorf("Expected argument to inner sexp to be 3. Got %d\n", firstInner.Integer.Contained)
 	}
 	etokens := []Token{START_SEXP, START_STRING, "s", "q", END_STRING, END_SEXP}
-	err, sexp, newStart = ParseSExpression(etokens, 0)
+	err, sexp, newStart = ParseSExpression(etokens, nil, 0)
 	if err == nil {
 		t.Error("Expected to get an error parsing an S-Expression that starts with a string")
 	}
@@ -157,7 +178,7 @@ func TestParseSExpression(t *testing.T) {
 
 func TestParse(t *testing.T) {
 	tokens := []Token{START_SEXP, START_NAME, "x", END_NAME, START_NUMBER, "0", END_NUMBER, END_SEXP, START_SEXP, START_NAME, "p", END_NAME, START_STRING, "h", "i", END_STRING, END_SEXP}
-	err, forms := Parse(tokens)
+	err, forms := Parse(tokens, nil)
 	if err != nil {
 		t.Error(err.Error())
 	}
@@ -174,13 +195,131 @@ func TestParse(t *testing.T) {
 		t.Errorf("Expected the second parsed S-Expression to contain the argument 'hi'. Got %s\n", secondFormArg)
 	}
 	etokens1 := []Token{START_SEXP, START_NAME, "h", END_NAME, END_SEXP, START_SEXP, "?", "end?", END_SEXP}
-	err, forms = Parse(etokens1)
+	err, forms = Parse(etokens1, nil)
 	if err == nil {
 		t.Error("Expected to get an error parsing unknown start token '?'.")
 	}
 	etokens2 := []Token{START_SEXP, START_NUMBER, "3", END_NUMBER, END_SEXP}
-	err, forms = Parse(etokens2)
+	err, forms = Parse(etokens2, nil)
 	if err == nil {
 		t.Error("Expected to get an error parsing an S-Expression that doesn't start with a name (error should propagate up)")
 	}
 }
+
+func TestParseCollection(t *testing.T) {
+	_, tokens, positions, _ := Lex("[1 (x) {\"a\" [b]}]", 0)
+	err, forms := Parse(tokens, positions)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	list, isSexp := forms[0].(SExpression)
+	if !isSexp || list.FormName.Contained != ListLiteralForm || len(list.Values) != 3 {
+		t.Fatalf("Expected a list literal with three elements. Got %v\n", forms[0])
+	}
+	mapping := list.Values[2].(SExpression)
+	if mapping.FormName.Contained != MapLiteralForm || len(mapping.Values) != 2 {
+		t.Errorf("Expected a map literal with a key and a value. Got %v\n", mapping)
+	}
+	if mapping.Position != (Position{"", 1, 8}) {
+		t.Errorf("Expected the map literal to be at column 8. Got %v\n", mapping.Position)
+	}
+	inner := mapping.Values[1].(SExpression)
+	if inner.FormName.Contained != ListLiteralForm || inner.Values[0].(Value).Name.Contained != "b" {
+		t.Errorf("Expected the map's value to be a list literal containing b. Got %v\n", inner)
+	}
+	for _, program := range []string{"[1 2", "{\"a\" 1", "[1 2}", "(x [1)]", "]"} {
+		_, tokens, positions, _ := Lex(program, 0)
+		if err, _ := Parse(tokens, positions); err == nil {
+			t.Errorf("Expected an error parsing %q\n", program)
+		}
+	}
+}
+
+func TestParseUnclosed(t *testing.T) {
+	tests := [...]struct {
+		Program  string
+		Expected string
+	}{
+		{"(", "line 1, column 1: Unclosed S-Expression encountered."},
+		{"(define (a (", "line 1, column 12: Unclosed S-Expression encountered."},
+		{"(f \"x", "line 1, column 4: Unclosed string encountered."},
+	}
+	for _, test := range tests {
+		// The lexer reports unterminated strings too, but the tokens it produced up to that point must still
+		// parse without panicking, for programs that embed the parser
+		_, tokens, positions, _ := Lex(test.Program, 0)
+		err, _ := Parse(tokens, positions)
+		if err == nil {
+			t.Errorf("Expected an error parsing %q\n", test.Program)
+		} else if !strings.HasPrefix(err.Error(), test.Expected) {
+			t.Errorf("Expected the error parsing %q to start with %q. Got %q\n", test.Program, test.Expected, err.Error())
+		}
+	}
+	truncated := [][]Token{
+		{START_NAME, "x"},
+		{START_NUMBER, "1"},
+		{START_STRING, "s"},
+		{START_COMMENT, ";"},
+		{START_SEXP, START_NAME, "f"},
+	}
+	for _, tokens := range truncated {
+		if err, _ := Parse(tokens, nil); err == nil || !strings.HasPrefix(err.Error(), "Unclosed") {
+			t.Errorf("Expected an unclosed error parsing %v. Got %v\n", tokens, err)
+		}
+	}
+}
+
+func TestParseNumberLiterals(t *testing.T) {
+	integers := map[string]int64{
+		"-5":        -5,
+		"1_000_000": 1000000,
+		"0x1F":      31,
+		"-0xff":     -255,
+		"0o17":      15,
+		"0b101":     5,
+		"0b_1010":   10,
+		"007":       7,
+	}
+	floats := map[string]float64{
+		"1e6":      1e6,
+		"-2.5":     -2.5,
+		"1.5e-3":   1.5e-3,
+		"6.02E+23": 6.02e23,
+		"1_000.5":  1000.5,
+	}
+	for literal, expected := range integers {
+		_, lexed, positions, _ := Lex(literal, 0)
+		err, value, _ := ParseNumber(lexed, positions, 0)
+		if err != nil {
+			t.Error(err.Error())
+		} else if value.Type != IntegerT || value.Integer.Contained != expected {
+			t.Errorf("Expected %s to be the integer %d. Got %v\n", literal, expected, Unwrap(value))
+		}
+	}
+	for literal, expected := range floats {
+		_, lexed, positions, _ := Lex(literal, 0)
+		err, value, _ := ParseNumber(lexed, positions, 0)
+		if err != nil {
+			t.Error(err.Error())
+		} else if value.Type != FloatT || value.Float.Contained != expected {
+			t.Errorf("Expected %s to be the float %f. Got %v\n", literal, expected, Unwrap(value))
+		}
+	}
+	invalid := []string{"1.2.3", "12ab", "1__0", "1_", "0x", "0b102", "1e", "99999999999999999999", "0x1p3", "1.5e+"}
+	for _, literal := range invalid {
+		_, lexed, positions, _ := Lex(literal, 0)
+		if lexed[0] != START_NUMBER {
+			t.Errorf("Expected %s to be lexed as a number\n", literal)
+			continue
+		}
+		if err, _, _ := ParseNumber(lexed, positions, 0); err == nil {
+			t.Errorf("Expected to get an error parsing the invalid number %s\n", literal)
+		}
+	}
+	// A minus sign only starts a number when it is followed by a digit
+	for _, name := range []string{"-", "-x", "count-5"} {
+		if _, lexed, _, _ := Lex(name, 0); lexed[0] != START_NAME {
+			t.Errorf("Expected %s to be lexed as a name\n", name)
+		}
+	}
+}
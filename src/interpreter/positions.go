@@ -0,0 +1,165 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+/**
+ * The place in a fig program that a token, value or S-Expression came from.
+ * Lines and columns start counting from 1, so a line of 0 means that the position is not known, as is the
+ * case for values created while a program runs rather than written in its source.
+ */
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (pos Position) Known() bool {
+	return pos.Line > 0
+}
+
+func (pos Position) String() string {
+	if pos.File == "" {
+		return fmt.Sprintf("line %d, column %d", pos.Line, pos.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", pos.File, pos.Line, pos.Column)
+}
+
+/**
+ * A call to a function defined in fig code, made from a particular place in a program.
+ * Calls made by Go code, such as those made to expand macros, have no known call site.
+ */
+type Frame struct {
+	Function string
+	CallSite Position
+}
+
+func (frame Frame) String() string {
+	if !frame.CallSite.Known() {
+		return "in " + frame.Function
+	}
+	return "in " + frame.Function + ", called at " + frame.CallSite.String()
+}
+
+// The most frames of a stack trace to include in an error message, so that deep recursion stays readable
+const maxFramesShown = 20
+
+/**
+ * An error that occurred at a particular place in a fig program.  When the source of the program is available,
+ * the line the error occurred on is included in the message with a caret pointing at the column.
+ * The stack lists the calls to functions that were being evaluated when the error occurred, innermost first.
+ */
+type PositionError struct {
+	Position Position
+	Err      error
+	Excerpt  string
+	Stack    []Frame
+}
+
+func (e PositionError) Error() string {
+	msg := e.Position.String() + ": " + e.Err.Error()
+	if e.Excerpt != "" {
+		msg += "\n    " + e.Excerpt + "\n    " + e.caret()
+	}
+	for i, frame := range e.Stack {
+		if i == maxFramesShown {
+			msg += fmt.Sprintf("\n  ... and %d more", len(e.Stack)-maxFramesShown)
+			break
+		}
+		msg += "\n  " + frame.String()
+	}
+	return msg
+}
+
+/**
+ * Produce a line with a caret pointing at the column of the error in the excerpt.
+ */
+func (e PositionError) caret() string {
+	// Keep tabs in the line so that the caret lines up with the column however wide tabs are displayed
+	caret := ""
+	for i, r := range []rune(e.Excerpt) {
+		if i >= e.Position.Column-1 {
+			break
+		}
+		if r == '\t' {
+			caret += "\t"
+		} else {
+			caret += " "
+		}
+	}
+	return caret + "^"
+}
+
+func (e PositionError) Unwrap() error {
+	return e.Err
+}
+
+/**
+ * Attach a position to an error, unless the position is not known or the error already has one, in which case
+ * the error is returned as it is.  Errors that already have a position occurred in a more deeply nested
+ * expression, so their position is the more precise one.
+ */
+func atPosition(err error, pos Position) error {
+	if err == nil || !pos.Known() {
+		return err
+	}
+	var posErr PositionError
+	if errors.As(err, &posErr) {
+		return err
+	}
+	return PositionError{pos, err, "", nil}
+}
+
+/**
+ * Record that an error occurred during a call to a function, adding the call to the error's stack trace.
+ * Only errors with a position, which come from code written in a program, have stack traces.
+ */
+func inFrame(err error, frame Frame) error {
+	posErr, isPosErr := err.(PositionError)
+	if !isPosErr {
+		return err
+	}
+	posErr.Stack = append(posErr.Stack, frame)
+	return posErr
+}
+
+/**
+ * Find the position of the code an expression was parsed from.
+ */
+func positionOf(thing interface{}) Position {
+	switch thing.(type) {
+	case Value:
+		return thing.(Value).Position
+	case SExpression:
+		return thing.(SExpression).Position
+	}
+	return Position{}
+}
+
+/**
+ * Add an excerpt of the source code to an error that has a position.  The source is the program the error
+ * occurred in if it came from the named file, and is otherwise read from the file the error occurred in.
+ */
+func withExcerpt(err error, program, file string) error {
+	posErr, isPosErr := err.(PositionError)
+	if !isPosErr || posErr.Excerpt != "" {
+		return err
+	}
+	if posErr.Position.File != file {
+		source, readErr := ioutil.ReadFile(posErr.Position.File)
+		if readErr != nil {
+			return err
+		}
+		program = string(source)
+	}
+	lines := strings.Split(program, "\n")
+	if posErr.Position.Line > len(lines) {
+		return err
+	}
+	posErr.Excerpt = strings.TrimRight(lines[posErr.Position.Line-1], "\r")
+	return posErr
+}
@@ -0,0 +1,77 @@
+package interpreter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPositionErrors(t *testing.T) {
+	tests := [...]struct {
+		Program  string
+		Expected string
+	}{
+		{"(define\n  (x 1)\n  (y (x z)))", "main.fig:3:9: Variable z not assigned.\n      (y (x z)))\n            ^"},
+		{"(define (x 1)))", "main.fig:1:15: Found a closing parenthesis without a matching opening parenthesis."},
+		{"\n(define (x 1)", "main.fig:2:1: Unclosed S-Expression encountered."},
+		{"(define (x ~1))", "main.fig:1:12: Unexpected character '~'.\n    (define (x ~1))\n               ^"},
+		{"(define (x [1 2)))", "main.fig:1:16: Expected ] but found )."},
+		{"(define (x 1))\n{\"a\" 1", "main.fig:2:1: Unclosed map encountered."},
+		{"(define (x {\"a\"}))", "main.fig:1:12: Map literals must contain an even number of keys and values."},
+		{"(define (x {1 2}))", "main.fig:1:13: Map literal keys must be strings or keywords."},
+		{"[1 2]]", "main.fig:1:6: Found a closing ] without a matching opening bracket."},
+		{"(define (x 'one\n'))", "main.fig:1:12: Unterminated string."},
+		{"(define (x (nope 1)))", "main.fig:1:12: No such function nope"},
+	}
+	for _, test := range tests {
+		env := NewEnvironment(nil)
+		env.File = "main.fig"
+		err := EvaluateProgram(test.Program, env)
+		if err == nil {
+			t.Errorf("Expected to get an error evaluating %s\n", test.Program)
+		} else if !strings.HasPrefix(err.Error(), test.Expected) {
+			t.Errorf("Expected error to start with\n%s\nGot\n%s\n", test.Expected, err.Error())
+		}
+	}
+	// Errors caught by try do not include their position
+	env := NewEnvironment(nil)
+	env.File = "main.fig"
+	err, value, _ := Evaluate(NewSExpression("try", NewSExpression("nope"),
+		NewSExpression("catch", NewName("e"), NewName("e"))), env)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	if message := value.Map.Data["message"].String.Contained; message != "No such function nope" {
+		t.Errorf("Expected the caught message to be 'No such function nope'. Got %s\n", message)
+	}
+}
+
+func TestStackTraces(t *testing.T) {
+	env := NewEnvironment(nil)
+	env.File = "main.fig"
+	fail := func(args ...interface{}) (Value, error) {
+		return Value{}, errors.New("Failed")
+	}
+	env.Define("fail", NewCallableFunction("fail", []string{}, fail))
+	program := "(define\n" +
+		"  (inner (function (x) (fail x)))\n" +
+		"  (outer (function (x) (let (y (inner x)) y)))\n" +
+		"  (z (outer 1)))"
+	err := EvaluateProgram(program, env)
+	var posErr PositionError
+	if !errors.As(err, &posErr) {
+		t.Fatalf("Expected an error with a position. Got %v\n", err)
+	}
+	expected := []Frame{{"inner", Position{"main.fig", 3, 32}}, {"outer", Position{"main.fig", 4, 6}}}
+	if len(posErr.Stack) != len(expected) {
+		t.Fatalf("Expected %d frames in the stack trace. Got %v\n", len(expected), posErr.Stack)
+	}
+	for i, frame := range expected {
+		if posErr.Stack[i] != frame {
+			t.Errorf("Expected frame %d to be %v. Got %v\n", i, frame, posErr.Stack[i])
+		}
+	}
+	if !strings.HasSuffix(err.Error(), "\n  in inner, called at main.fig:3:32\n  in outer, called at main.fig:4:6") {
+		t.Errorf("Expected the stack trace to be included in the error message. Got\n%s\n", err.Error())
+	}
+}
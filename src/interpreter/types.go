@@ -1,7 +1,9 @@
 package interpreter
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 )
 
 // Types
@@ -21,6 +23,8 @@ const (
 	SpecialFormT ValueType = iota
 	ListT        ValueType = iota
 	MapT         ValueType = iota
+	KeywordT     ValueType = iota
+	NilT         ValueType = iota
 	ValueT       ValueType = iota
 )
 
@@ -50,6 +54,12 @@ type BooleanLiteral struct {
 	Contained bool
 }
 
+type Keyword struct {
+	Contained string // The keyword without its leading colon
+}
+
+type Nil struct{}
+
 func (s StringLiteral) Type() ValueType {
 	return StringT
 }
@@ -70,12 +80,73 @@ func (b BooleanLiteral) Type() ValueType {
 	return BooleanT
 }
 
+func (k Keyword) Type() ValueType {
+	return KeywordT
+}
+
+func (n Nil) Type() ValueType {
+	return NilT
+}
+
+/**
+ * Names can end up in data when code is quoted.  They are printed and serialized as plain strings.
+ */
+
+func (n Name) String() string {
+	return n.Contained
+}
+
+func (n Name) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.Contained)
+}
+
+func (n Name) MarshalYAML() (interface{}, error) {
+	return n.Contained, nil
+}
+
+/**
+ * Keywords such as :host evaluate to themselves and are mostly used as map keys.  They are printed the way they
+ * are written, but serialized as plain strings without the colon, the same as the keys of maps.
+ */
+
+func (k Keyword) String() string {
+	return ":" + k.Contained
+}
+
+func (k Keyword) MarshalJSON() ([]byte, error) {
+	return json.Marshal(k.Contained)
+}
+
+func (k Keyword) MarshalYAML() (interface{}, error) {
+	return k.Contained, nil
+}
+
+/**
+ * Nil is the explicit absence of a value, as opposed to a name that has not been defined.  Unwrapping nil
+ * produces a Nil rather than Go's nil, so that it is not mistaken for a value that cannot be unwrapped, and it
+ * is serialized as null.  YAML writes it as null rather than ~, which YAML reads as the same value, since the
+ * YAML encoder quotes a ~ produced by a marshaler.
+ */
+
+func (n Nil) String() string {
+	return "nil"
+}
+
+func (n Nil) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+func (n Nil) MarshalYAML() (interface{}, error) {
+	return nil, nil
+}
+
 // S-Expressions
 
 type SExpression struct {
 	FormName Name
 	Type     ValueType
 	Values   []interface{} // Values or S-Expressions
+	Position Position
 }
 
 // Functions
@@ -87,14 +158,21 @@ type Builtin func(...interface{}) (Value, error)
  * as well as builtin functions.  In the case of user-defined fucntions, a body S-Expression
  * is provided to be evaluated until a builtin is reached that can be executed as Go code.
  * The IsCallable and Callable fields handle the latter case.
+ * Macros are user-defined functions that receive their arguments as unevaluated code and produce new code
+ * to be evaluated in place of the macro call.
  */
 type Function struct {
 	FunctionName  Name
 	ArgumentNames []Name
 	Body          interface{} // Can be a Value or an S-Expression
 	IsCallable    bool
-	Scope         Environment
+	Scope         *Environment
 	Callable      Builtin
+	IsMacro       bool
+}
+
+func (fn Function) String() string {
+	return "<function " + fn.FunctionName.Contained + ">"
 }
 
 func (fn Function) Call(unwrapped ...interface{}) (Value, error) {
@@ -105,6 +183,25 @@ func (fn Function) Call(unwrapped ...interface{}) (Value, error) {
 	}
 }
 
+// Errors
+
+/**
+ * An error raised by a fig program, containing a message and, optionally, a value with more information
+ * about what went wrong.  Fig programs can recover from errors using `try`.
+ */
+type FigError struct {
+	Message string
+	Data    Value
+	HasData bool
+}
+
+func (e FigError) Error() string {
+	if e.HasData {
+		return fmt.Sprintf("%s %v", e.Message, Unwrap(e.Data))
+	}
+	return e.Message
+}
+
 // Another OR type. Either a literal, a name, a function, or a list
 type Value struct {
 	Type     ValueType
@@ -112,11 +209,13 @@ type Value struct {
 	Integer  IntegerLiteral
 	Float    FloatLiteral
 	Name     Name
+	Keyword  Keyword
 	Boolean  BooleanLiteral
 	Function Function
 	List     List
 	Map      Mapping
-	Ignored  bool // Should we ignore the value when producing an output config file?
+	Ignored  bool     // Should we ignore the value when producing an output config file?
+	Position Position // Where the value was written in a program, if it was
 }
 
 // Lists
@@ -149,4 +248,8 @@ const (
 	END_COMMENT   Token = "[END_COMMENT]"
 	END_NUMBER    Token = "[END_NUMBER]"
 	END_NAME      Token = "[END_NAME]"
+	START_LIST    Token = "[START_LIST]"
+	END_LIST      Token = "[END_LIST]"
+	START_MAP     Token = "[START_MAP]"
+	END_MAP       Token = "[END_MAP]"
 )
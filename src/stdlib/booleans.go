@@ -3,6 +3,7 @@ package stdlib
 import (
 	uni "../interpreter"
 	"errors"
+	"reflect"
 )
 
 func ToBoolKeyword(value bool) uni.Value {
@@ -37,6 +38,14 @@ func SLIB_IsZero(arguments ...interface{}) (uni.Value, error) {
 	return ToBoolKeyword(isZero), nil
 }
 
+func SLIB_IsNil(arguments ...interface{}) (uni.Value, error) {
+	if len(arguments) != 1 {
+		return uni.Value{}, errors.New("Nil predicate function expects exactly one argument.")
+	}
+	_, isNil := arguments[0].(uni.Nil)
+	return ToBoolKeyword(isNil), nil
+}
+
 func SLIB_And(arguments ...interface{}) (uni.Value, error) {
 	if len(arguments) < 2 {
 		return uni.Value{}, errors.New("And function expects two or more arguments.")
@@ -69,28 +78,63 @@ func SLIB_Equal(arguments ...interface{}) (uni.Value, error) {
 	if len(arguments) < 2 {
 		return uni.Value{}, errors.New("Equal function expects two or more arguments.")
 	}
+	// Nil is only equal to nil, so it can be compared with values of any type
+	nils := 0
+	for _, argument := range arguments {
+		if _, isNil := argument.(uni.Nil); isNil {
+			nils++
+		}
+	}
+	if nils > 0 {
+		return ToBoolKeyword(nils == len(arguments)), nil
+	}
+	// Values of different types are never equal, so strings are not equal to keywords or names with the same text
 	result := true
 	switch arguments[0].(type) {
 	case int64:
 		value := arguments[0].(int64)
 		for i := 1; i < len(arguments) && result; i++ {
-			result = value == arguments[i].(int64)
+			other, isSameType := arguments[i].(int64)
+			result = isSameType && value == other
 		}
 	case float64:
 		value := arguments[0].(float64)
 		for i := 1; i < len(arguments) && result; i++ {
-			result = value == arguments[i].(float64)
+			other, isSameType := arguments[i].(float64)
+			result = isSameType && value == other
 		}
 	case string:
 		value := arguments[0].(string)
 		for i := 1; i < len(arguments) && result; i++ {
-			result = value == arguments[i].(string)
+			other, isSameType := arguments[i].(string)
+			result = isSameType && value == other
 		}
 	case bool:
 		value := arguments[0].(bool)
 		for i := 1; i < len(arguments) && result; i++ {
-			result = value == arguments[i].(bool)
+			other, isSameType := arguments[i].(bool)
+			result = isSameType && value == other
 		}
+	case uni.Name:
+		value := arguments[0].(uni.Name)
+		for i := 1; i < len(arguments) && result; i++ {
+			other, isSameType := arguments[i].(uni.Name)
+			result = isSameType && value == other
+		}
+	case uni.Keyword:
+		value := arguments[0].(uni.Keyword)
+		for i := 1; i < len(arguments) && result; i++ {
+			other, isSameType := arguments[i].(uni.Keyword)
+			result = isSameType && value == other
+		}
+	case []interface{}, map[string]interface{}:
+		// Lists and maps are equal when they contain equal values in the same places
+		for i := 1; i < len(arguments) && result; i++ {
+			result = reflect.DeepEqual(arguments[0], arguments[i])
+		}
+	default:
+		// Functions cannot be compared, so they are never equal to anything
+		result = false
 	}
 	return ToBoolKeyword(result), nil
 }
@@ -0,0 +1,64 @@
+package stdlib
+
+import (
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	testExpressions(t, map[string]string{
+		`(= 1 1 1)`:     `true`,
+		`(= "a" "b")`:   `false`,
+		`(= :a :a)`:     `true`,
+		`(= nil nil)`:   `true`,
+		`(= nil false)`: `false`,
+		// Quoted names are equal only to the same name
+		`(= (quote a) (quote a))`:             `true`,
+		`(= (quote a) (quote b))`:             `false`,
+		`(= (quote a) (quote a) (quote b))`:   `false`,
+		`(= (quote a) "a")`:                   `false`,
+		`(= (first (quote (x y))) (quote x))`: `true`,
+	})
+}
+
+func TestEqualMixedTypes(t *testing.T) {
+	// Values of different types are unequal rather than being an error
+	testExpressions(t, map[string]string{
+		`(= :a "a")`:                            `false`,
+		`(= "a" :a)`:                            `false`,
+		`(= :a :a "a")`:                         `false`,
+		`(= 1 1.0)`:                             `false`,
+		`(= 1 "1")`:                             `false`,
+		`(= true "true")`:                       `false`,
+		`(= :a (quote a))`:                      `false`,
+		`(= (get {:a 1} "a") (get {"a" 1} :a))`: `true`,
+	})
+}
+
+func TestEqualCollections(t *testing.T) {
+	// Lists and maps are compared by their contents, and are never equal to values of other types
+	testExpressions(t, map[string]string{
+		`(= [1] 2)`:                             `false`,
+		`(= 2 [1])`:                             `false`,
+		`(= [1] [2])`:                           `false`,
+		`(= [1 [2]] [1 [2]])`:                   `true`,
+		`(= [1] [1] [1 2])`:                     `false`,
+		`(= [1] [1.0])`:                         `false`,
+		`(= {"k" 1} "x")`:                       `false`,
+		`(= {"k" 1} {"k" 1})`:                   `true`,
+		`(= {"k" 1} {"k" 2})`:                   `false`,
+		`(= {"k" [nil]} {"k" [nil]})`:           `true`,
+		`(= [] {})`:                             `false`,
+		`(= (function (x) x) (function (x) x))`: `false`,
+		`(= upcase upcase)`:                     `false`,
+	})
+}
+
+func TestBooleansThroughBuiltins(t *testing.T) {
+	// Booleans taken out of lists and maps by builtins are still booleans
+	testExpressions(t, map[string]string{
+		`(if (first [true]) 1 2)`:             `1`,
+		`(if (get {"on" false} "on") 1 2)`:    `2`,
+		`(not (get-in {"a" [true]} ["a" 0]))`: `false`,
+		`(= (first [true]) true)`:             `true`,
+	})
+}
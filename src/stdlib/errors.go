@@ -0,0 +1,31 @@
+package stdlib
+
+import (
+	uni "../interpreter"
+	"errors"
+)
+
+/**
+ * Raise an error with a message and, optionally, a value with more information about what went wrong.
+ */
+func SLIB_Error(arguments ...interface{}) (uni.Value, error) {
+	if len(arguments) != 1 && len(arguments) != 2 {
+		return uni.Value{}, errors.New("Error function expects a message and optionally one value to attach.")
+	}
+	switch arguments[0].(type) {
+	case string:
+		break
+	default:
+		return uni.Value{}, errors.New("Error function expects its first argument to be a string message.")
+	}
+	figErr := uni.FigError{Message: arguments[0].(string)}
+	if len(arguments) == 2 {
+		data, err := uni.Wrap(arguments[1])
+		if err != nil {
+			return uni.Value{}, err
+		}
+		figErr.Data = data
+		figErr.HasData = true
+	}
+	return uni.Value{}, figErr
+}
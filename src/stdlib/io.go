@@ -9,7 +9,7 @@ import (
 
 func SLIB_Print(arguments ...interface{}) (uni.Value, error) {
 	fmt.Println(arguments...)
-	return uni.Value{}, nil
+	return uni.NewNil(), nil
 }
 
 /**
@@ -3,8 +3,22 @@ package stdlib
 import (
 	uni "../interpreter"
 	"errors"
+	"fmt"
 )
 
+/**
+ * Determine the key a map argument stands for.  Keywords are used as keys by their name without the colon.
+ */
+func mapKey(key interface{}) (string, bool) {
+	switch key.(type) {
+	case string:
+		return key.(string), true
+	case uni.Keyword:
+		return key.(uni.Keyword).Contained, true
+	}
+	return "", false
+}
+
 func SLIB_Map(arguments ...interface{}) (uni.Value, error) {
 	mapping := uni.NewMap()
 	if len(arguments) == 0 {
@@ -14,19 +28,16 @@ func SLIB_Map(arguments ...interface{}) (uni.Value, error) {
 		return mapping, errors.New("Must have an even number of arguments to create a map.")
 	}
 	for i := 0; i < len(arguments); i += 2 {
-		key := arguments[i]
+		key, isKey := mapKey(arguments[i])
 		value := arguments[i+1]
-		switch key.(type) {
-		case string:
-			break
-		default:
-			return mapping, errors.New("All keys must be strings.")
+		if !isKey {
+			return mapping, errors.New("All keys must be strings or keywords.")
 		}
 		wrapped, err := uni.Wrap(value)
 		if err != nil {
 			return mapping, err
 		}
-		mapping.Map.Data[key.(string)] = wrapped
+		mapping.Map.Data[key] = wrapped
 	}
 	return mapping, nil
 }
@@ -52,19 +63,16 @@ func SLIB_Associate(arguments ...interface{}) (uni.Value, error) {
 	}
 	// Add all the new key-value pairs
 	for i := 1; i < len(arguments); i += 2 {
-		k := arguments[i]
+		k, isKey := mapKey(arguments[i])
 		v := arguments[i+1]
-		switch k.(type) {
-		case string:
-			break
-		default:
-			return mapping, errors.New("Associate function expects all the new keys to be strings.")
+		if !isKey {
+			return mapping, errors.New("Associate function expects all the new keys to be strings or keywords.")
 		}
 		wrapped, err := uni.Wrap(v)
 		if err != nil {
 			return mapping, err
 		}
-		mapping.Map.Data[k.(string)] = wrapped
+		mapping.Map.Data[k] = wrapped
 	}
 	return mapping, nil
 }
@@ -79,14 +87,11 @@ func SLIB_GetMap(arguments ...interface{}) (uni.Value, error) {
 	default:
 		return uni.Value{}, errors.New("Get function expects first argument to be a map.")
 	}
-	switch arguments[1].(type) {
-	case string:
-		break
-	default:
-		return uni.Value{}, errors.New("Get function expects second argument to be a string key.")
+	key, isKey := mapKey(arguments[1])
+	if !isKey {
+		return uni.Value{}, errors.New("Get function expects second argument to be a string or keyword key.")
 	}
 	mapping := arguments[0].(map[string]interface{})
-	key := arguments[1].(string)
 	wrapped, err := uni.Wrap(mapping[key])
 	return wrapped, err
 }
@@ -110,3 +115,371 @@ func SLIB_Keys(arguments ...interface{}) (uni.Value, error) {
 	return list, nil
 
 }
+
+/**
+ * Wrap the map arguments to a merge function, checking that each of them is a map.
+ */
+func mapArguments(name string, arguments []interface{}) ([]uni.Value, error) {
+	if len(arguments) < 1 {
+		return nil, errors.New(name + " function expects at least one map argument.")
+	}
+	maps := make([]uni.Value, len(arguments))
+	for i, argument := range arguments {
+		switch argument.(type) {
+		case map[string]interface{}:
+			break
+		default:
+			return nil, errors.New(name + " function expects all of its arguments to be maps.")
+		}
+		wrapped, err := uni.Wrap(argument)
+		if err != nil {
+			return nil, err
+		}
+		maps[i] = wrapped
+	}
+	return maps, nil
+}
+
+/**
+ * Combine maps, with the keys of later maps replacing those of earlier ones.
+ */
+func SLIB_Merge(arguments ...interface{}) (uni.Value, error) {
+	maps, err := mapArguments("Merge", arguments)
+	if err != nil {
+		return uni.Value{}, err
+	}
+	merged := uni.NewMap()
+	for _, mapping := range maps {
+		for k, v := range mapping.Map.Data {
+			merged.Map.Data[k] = v
+		}
+	}
+	return merged, nil
+}
+
+func deepMerge(strategy uni.MergeStrategy, maps []uni.Value) uni.Value {
+	merged := uni.NewMap()
+	for _, mapping := range maps {
+		merged = uni.DeepMerge(merged, mapping, strategy)
+	}
+	return merged
+}
+
+/**
+ * Combine maps, recursively merging the maps found under the same key and replacing everything else,
+ * including lists, with the values of later maps.
+ */
+func SLIB_DeepMerge(arguments ...interface{}) (uni.Value, error) {
+	maps, err := mapArguments("Deep merge", arguments)
+	if err != nil {
+		return uni.Value{}, err
+	}
+	return deepMerge(uni.MergeStrategy{}, maps), nil
+}
+
+/**
+ * Deep merge maps with a strategy for combining lists, written as "replace", "append" or "merge-by-key:key".
+ */
+func SLIB_DeepMergeWith(arguments ...interface{}) (uni.Value, error) {
+	if len(arguments) < 2 {
+		return uni.Value{}, errors.New("Deep merge with function expects a strategy and at least one map.")
+	}
+	spec, isKey := mapKey(arguments[0])
+	if !isKey {
+		return uni.Value{}, errors.New("Deep merge with function expects its strategy to be a string or keyword.")
+	}
+	strategy, err := uni.ParseMergeStrategy(spec)
+	if err != nil {
+		return uni.Value{}, err
+	}
+	maps, err := mapArguments("Deep merge with", arguments[1:])
+	if err != nil {
+		return uni.Value{}, err
+	}
+	return deepMerge(strategy, maps), nil
+}
+
+/**
+ * Check that a path into nested maps and lists is a list of map keys, which are strings or keywords, and list
+ * indices, which are integers.  Produces the path with keywords replaced by the keys they stand for.
+ */
+func pathSegments(name string, path interface{}) ([]interface{}, error) {
+	switch path.(type) {
+	case []interface{}:
+		break
+	default:
+		return nil, errors.New(name + " function expects its path to be a list of keys and indices.")
+	}
+	segments := make([]interface{}, len(path.([]interface{})))
+	for i, segment := range path.([]interface{}) {
+		if key, isKey := mapKey(segment); isKey {
+			segments[i] = key
+		} else if index, isIndex := segment.(int64); isIndex {
+			segments[i] = index
+		} else {
+			errMsg := fmt.Sprintf("%s function expects each part of its path to be a string, keyword or integer. Got %v.",
+				name, segment)
+			return nil, errors.New(errMsg)
+		}
+	}
+	return segments, nil
+}
+
+/**
+ * Describe the part of a path leading up to a segment, for error messages.
+ */
+func pathTo(path []interface{}, i int) string {
+	if i == 0 {
+		return "the start of the path"
+	}
+	return fmt.Sprintf("path %v", path[:i])
+}
+
+/**
+ * Describe the kind of a value, for error messages.
+ */
+func kindOf(value uni.Value) string {
+	switch value.Type {
+	case uni.MapT:
+		return "a map"
+	case uni.ListT:
+		return "a list"
+	case uni.StringT:
+		return "a string"
+	case uni.IntegerT:
+		return "an integer"
+	case uni.FloatT:
+		return "a float"
+	case uni.BooleanT:
+		return "a boolean"
+	case uni.KeywordT:
+		return "a keyword"
+	case uni.NameT:
+		return "a name"
+	case uni.NilT:
+		return "nil"
+	case uni.FunctionT:
+		return "a function"
+	}
+	return "a value"
+}
+
+/**
+ * Find the value that one segment of a path leads to from a map or list.  Produces an error naming the segment
+ * if the value is not a map or list that the segment can be looked up in, and reports whether the segment was
+ * found separately, so that callers can decide what to do about missing keys and indices.
+ */
+func pathStep(value uni.Value, path []interface{}, i int) (uni.Value, bool, error) {
+	switch path[i].(type) {
+	case string:
+		key := path[i].(string)
+		if value.Type != uni.MapT {
+			errMsg := fmt.Sprintf("Cannot look up the key %q at %s, which is %s rather than a map.",
+				key, pathTo(path, i), kindOf(value))
+			return uni.Value{}, false, errors.New(errMsg)
+		}
+		child, found := value.Map.Data[key]
+		return child, found, nil
+	default:
+		index := path[i].(int64)
+		if value.Type != uni.ListT {
+			errMsg := fmt.Sprintf("Cannot look up the index %d at %s, which is %s rather than a list.",
+				index, pathTo(path, i), kindOf(value))
+			return uni.Value{}, false, errors.New(errMsg)
+		}
+		if index < 0 || index >= int64(len(value.List.Data)) {
+			return uni.Value{}, false, nil
+		}
+		return value.List.Data[index], true, nil
+	}
+}
+
+/**
+ * The error produced when a segment of a path is not found.
+ */
+func missingSegment(value uni.Value, path []interface{}, i int) error {
+	if key, isKey := path[i].(string); isKey {
+		return errors.New(fmt.Sprintf("No key %q in the map at %s.", key, pathTo(path, i)))
+	}
+	errMsg := fmt.Sprintf("No index %d in the list of %d elements at %s.", path[i], len(value.List.Data), pathTo(path, i))
+	return errors.New(errMsg)
+}
+
+/**
+ * Produce a copy of a map or list with the value that one segment of a path leads to replaced.
+ */
+func replaceStep(value uni.Value, segment interface{}, replacement uni.Value) uni.Value {
+	if key, isKey := segment.(string); isKey {
+		data := make(map[string]uni.Value, len(value.Map.Data)+1)
+		for k, v := range value.Map.Data {
+			data[k] = v
+		}
+		data[key] = replacement
+		value.Map = uni.Mapping{Data: data}
+		return value
+	}
+	data := make([]uni.Value, len(value.List.Data))
+	copy(data, value.List.Data)
+	data[segment.(int64)] = replacement
+	value.List = uni.List{Data: data}
+	return value
+}
+
+/**
+ * Produce a copy of a nested value with the value at the end of a path replaced by the result of an update.
+ * The values along the path are copied, so the original value is left unchanged.  When create is true, maps
+ * are created for keys that are missing along the way, and otherwise every segment of the path must exist.
+ */
+func updatePath(value uni.Value, path []interface{}, i int, create bool,
+	update func(uni.Value) (uni.Value, error)) (uni.Value, error) {
+	if i == len(path) {
+		return update(value)
+	}
+	child, found, err := pathStep(value, path, i)
+	if err != nil {
+		return uni.Value{}, err
+	}
+	if !found {
+		if _, isKey := path[i].(string); !create || !isKey {
+			return uni.Value{}, missingSegment(value, path, i)
+		}
+		child = uni.NewMap()
+	}
+	updated, err := updatePath(child, path, i+1, create, update)
+	if err != nil {
+		return uni.Value{}, err
+	}
+	return replaceStep(value, path[i], updated), nil
+}
+
+/**
+ * Get the value at the end of a path of keys and indices through nested maps and lists.  If a default value
+ * is provided, it is produced when part of the path is missing instead of an error.
+ */
+func SLIB_GetIn(arguments ...interface{}) (uni.Value, error) {
+	if len(arguments) != 2 && len(arguments) != 3 {
+		return uni.Value{}, errors.New("Get in function expects a map or list, a path and optionally a default value.")
+	}
+	path, err := pathSegments("Get in", arguments[1])
+	if err != nil {
+		return uni.Value{}, err
+	}
+	value, err := uni.Wrap(arguments[0])
+	if err != nil {
+		return uni.Value{}, err
+	}
+	for i := range path {
+		child, found, err := pathStep(value, path, i)
+		if err != nil {
+			return uni.Value{}, err
+		}
+		if !found && len(arguments) == 3 {
+			return uni.Wrap(arguments[2])
+		}
+		if !found {
+			return uni.Value{}, missingSegment(value, path, i)
+		}
+		value = child
+	}
+	return value, nil
+}
+
+/**
+ * Set the value at the end of a path through nested maps and lists, creating maps for any missing keys.
+ */
+func SLIB_AssocIn(arguments ...interface{}) (uni.Value, error) {
+	if len(arguments) != 3 {
+		return uni.Value{}, errors.New("Assoc in function expects a map or list, a path and a value.")
+	}
+	path, err := pathSegments("Assoc in", arguments[1])
+	if err != nil {
+		return uni.Value{}, err
+	}
+	value, err := uni.Wrap(arguments[0])
+	if err != nil {
+		return uni.Value{}, err
+	}
+	newValue, err := uni.Wrap(arguments[2])
+	if err != nil {
+		return uni.Value{}, err
+	}
+	return updatePath(value, path, 0, true, func(uni.Value) (uni.Value, error) {
+		return newValue, nil
+	})
+}
+
+/**
+ * Replace the value at the end of a path through nested maps and lists with the result of calling a function
+ * with the value and any additional arguments.
+ */
+func SLIB_UpdateIn(arguments ...interface{}) (uni.Value, error) {
+	if len(arguments) < 3 {
+		errMsg := "Update in function expects a map or list, a path, a function and any additional arguments."
+		return uni.Value{}, errors.New(errMsg)
+	}
+	path, err := pathSegments("Update in", arguments[1])
+	if err != nil {
+		return uni.Value{}, err
+	}
+	fn, isFunction := arguments[2].(uni.Function)
+	if !isFunction {
+		return uni.Value{}, errors.New("Update in function expects its third argument to be a function.")
+	}
+	value, err := uni.Wrap(arguments[0])
+	if err != nil {
+		return uni.Value{}, err
+	}
+	extra := make([]uni.Value, len(arguments)-3)
+	for i, argument := range arguments[3:] {
+		if extra[i], err = uni.Wrap(argument); err != nil {
+			return uni.Value{}, err
+		}
+	}
+	return updatePath(value, path, 0, false, func(old uni.Value) (uni.Value, error) {
+		return uni.Apply(fn, append([]uni.Value{old}, extra...)...)
+	})
+}
+
+/**
+ * Remove the key or list element at the end of a path through nested maps and lists.
+ */
+func SLIB_DissocIn(arguments ...interface{}) (uni.Value, error) {
+	if len(arguments) != 2 {
+		return uni.Value{}, errors.New("Dissoc in function expects a map or list and a path.")
+	}
+	path, err := pathSegments("Dissoc in", arguments[1])
+	if err != nil {
+		return uni.Value{}, err
+	}
+	if len(path) == 0 {
+		return uni.Value{}, errors.New("Dissoc in function expects a path with at least one key or index.")
+	}
+	value, err := uni.Wrap(arguments[0])
+	if err != nil {
+		return uni.Value{}, err
+	}
+	last := len(path) - 1
+	return updatePath(value, path[:last], 0, false, func(parent uni.Value) (uni.Value, error) {
+		if _, found, err := pathStep(parent, path, last); err != nil || !found {
+			if err == nil {
+				err = missingSegment(parent, path, last)
+			}
+			return uni.Value{}, err
+		}
+		if key, isKey := path[last].(string); isKey {
+			data := make(map[string]uni.Value, len(parent.Map.Data))
+			for k, v := range parent.Map.Data {
+				if k != key {
+					data[k] = v
+				}
+			}
+			parent.Map = uni.Mapping{Data: data}
+			return parent, nil
+		}
+		index := path[last].(int64)
+		data := make([]uni.Value, 0, len(parent.List.Data)-1)
+		data = append(data, parent.List.Data[:index]...)
+		parent.List = uni.List{Data: append(data, parent.List.Data[index+1:]...)}
+		return parent, nil
+	})
+}
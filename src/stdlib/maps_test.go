@@ -0,0 +1,136 @@
+package stdlib
+
+import (
+	uni "../interpreter"
+	"reflect"
+	"testing"
+)
+
+// A configuration with maps nested inside of lists inside of maps, to look up paths in
+const config = `{"name" "api" "databases" [{"host" "a" "accounts" {"root" "hunter2"}} {"host" "b"}]}`
+
+func TestGetIn(t *testing.T) {
+	testExpressions(t, map[string]string{
+		`(get-in ` + config + ` ["name"])`:                           `"api"`,
+		`(get-in ` + config + ` [:databases 0 :accounts "root"])`:    `"hunter2"`,
+		`(get-in ` + config + ` ["databases" 1])`:                    `{"host" "b"}`,
+		`(get-in ` + config + ` [])`:                                 config,
+		`(get-in [[1 2] [3 4]] [1 0])`:                               `3`,
+		`(get-in ` + config + ` ["databases" 1 "accounts"] "none")`:  `"none"`,
+		`(get-in ` + config + ` ["databases" 5 "host"] "none")`:      `"none"`,
+		`(get-in ` + config + ` ["databases" -1] nil)`:               `nil`,
+		`(get-in ` + config + ` ["name"] "none")`:                    `"api"`,
+		`(get-in ` + config + ` ["databases" 0 "accounts" "admin"])`: `error: No key "admin" in the map at path [databases 0 accounts].`,
+		`(get-in ` + config + ` ["databases" 2])`:                    `error: No index 2 in the list of 2 elements at path [databases].`,
+		`(get-in ` + config + ` ["databases" -1])`:                   `error: No index -1 in the list of 2 elements at path [databases].`,
+		`(get-in ` + config + ` ["missing"])`:                        `error: No key "missing" in the map at the start of the path.`,
+		`(get-in ` + config + ` ["databases" "host"])`:               `error: Cannot look up the key "host" at path [databases], which is a list rather than a map.`,
+		`(get-in ` + config + ` ["name" 0] "none")`:                  `error: Cannot look up the index 0 at path [name], which is a string rather than a list.`,
+		`(get-in ` + config + ` "name")`:                             `error: Get in function expects its path to be a list of keys and indices.`,
+		`(get-in ` + config + ` [1.5])`:                              `error: Get in function expects each part of its path to be a string, keyword or integer.`,
+		`(get-in ` + config + `)`:                                    `error: Not enough arguments passed to get-in`,
+	})
+}
+
+func TestAssocIn(t *testing.T) {
+	testExpressions(t, map[string]string{
+		`(assoc-in {"a" 1} ["b"] 2)`:                                       `{"a" 1 "b" 2}`,
+		`(assoc-in {"a" {"b" 1}} [:a :b] 2)`:                               `{"a" {"b" 2}}`,
+		`(assoc-in {} ["a" "b" "c"] 1)`:                                    `{"a" {"b" {"c" 1}}}`,
+		`(assoc-in {"a" {"x" 0}} ["a" "b" "c"] 1)`:                         `{"a" {"x" 0 "b" {"c" 1}}}`,
+		`(assoc-in [1 [2 3]] [1 0] "two")`:                                 `[1 ["two" 3]]`,
+		`(assoc-in ` + config + ` ["databases" 1 "accounts" "root"] "pw")`: `{"name" "api" "databases" [{"host" "a" "accounts" {"root" "hunter2"}} {"host" "b" "accounts" {"root" "pw"}}]}`,
+		`(assoc-in [1 2] [2] 3)`:                                           `error: No index 2 in the list of 2 elements at the start of the path.`,
+		`(assoc-in [1 2] [-1] 3)`:                                          `error: No index -1 in the list of 2 elements at the start of the path.`,
+		`(assoc-in {"a" [1]} ["a" 1 "b"] 3)`:                               `error: No index 1 in the list of 1 elements at path [a].`,
+		`(assoc-in {"a" 1} ["a" "b"] 2)`:                                   `error: Cannot look up the key "b" at path [a], which is an integer rather than a map.`,
+		`(assoc-in {"a" 1} ["a"])`:                                         `error: Not enough arguments passed to assoc-in`,
+	})
+}
+
+func TestUpdateIn(t *testing.T) {
+	testExpressions(t, map[string]string{
+		`(update-in {"a" {"n" 1}} ["a" "n"] (function (n) (+ n 1)))`: `{"a" {"n" 2}}`,
+		`(update-in {"a" [1 2]} ["a" 1] + 10)`:                       `{"a" [1 12]}`,
+		`(update-in {"a" [1 2]} ["a"] append 3)`:                     `{"a" [1 2 3]}`,
+		`(update-in {"a" 1} [] (function (m) (assoc m "b" 2)))`:      `{"a" 1 "b" 2}`,
+		`(update-in {"a" {}} ["a" "n"] + 1)`:                         `error: No key "n" in the map at path [a].`,
+		`(update-in {"a" [1]} ["a" 3] + 1)`:                          `error: No index 3 in the list of 1 elements at path [a].`,
+		`(update-in {"a" [1]} ["a" -1] + 1)`:                         `error: No index -1 in the list of 1 elements at path [a].`,
+		`(update-in {"a" 1} ["a"] 2)`:                                `error: Update in function expects its third argument to be a function.`,
+		`(update-in {"a" 1} ["a"] concat "x")`:                       `error: Concatenate function expects string arguments. Got an integer.`,
+	})
+}
+
+func TestDissocIn(t *testing.T) {
+	testExpressions(t, map[string]string{
+		`(dissoc-in {"a" 1 "b" 2} ["a"])`:                       `{"b" 2}`,
+		`(dissoc-in {"a" {"b" 1 "c" 2}} [:a :b])`:               `{"a" {"c" 2}}`,
+		`(dissoc-in [1 2 3] [1])`:                               `[1 3]`,
+		`(dissoc-in {"a" [1 2 3]} ["a" 0])`:                     `{"a" [2 3]}`,
+		`(dissoc-in {"a" [1 2 3]} ["a" 2])`:                     `{"a" [1 2]}`,
+		`(dissoc-in ` + config + ` ["databases" 0 "accounts"])`: `{"name" "api" "databases" [{"host" "a"} {"host" "b"}]}`,
+		`(dissoc-in {"a" [1 2 3]} ["a" 3])`:                     `error: No index 3 in the list of 3 elements at path [a].`,
+		`(dissoc-in {"a" [1 2 3]} ["a" -1])`:                    `error: No index -1 in the list of 3 elements at path [a].`,
+		`(dissoc-in {"a" {}} ["a" "b"])`:                        `error: No key "b" in the map at path [a].`,
+		`(dissoc-in {"a" {}} ["x" "b"])`:                        `error: No key "x" in the map at the start of the path.`,
+		`(dissoc-in {"a" 1} [])`:                                `error: Dissoc in function expects a path with at least one key or index.`,
+	})
+}
+
+func TestMerge(t *testing.T) {
+	testExpressions(t, map[string]string{
+		`(merge {"a" 1 "b" {"x" 1}} {"b" {"y" 2}})`:      `{"a" 1 "b" {"y" 2}}`,
+		`(merge {"a" 1} {"a" 2} {"c" 3})`:                `{"a" 2 "c" 3}`,
+		`(merge {"a" 1} [1])`:                            `error: Merge function expects all of its arguments to be maps.`,
+		`(merge "a" {"a" 1})`:                            `error: Merge function expects all of its arguments to be maps.`,
+		`(merge {"a" 1})`:                                `error: Not enough arguments passed to merge`,
+		`(deep-merge {"a" 1 "b" {"x" 1}} {"b" {"y" 2}})`: `{"a" 1 "b" {"x" 1 "y" 2}}`,
+		`(deep-merge {"a" [1 2]} {"a" [3]})`:             `{"a" [3]}`,
+		`(deep-merge {"a" {"b" 1}} {"a" 2} {"c" nil})`:   `{"a" 2 "c" nil}`,
+		`(deep-merge {"a" 1} nil)`:                       `error: Deep merge function expects all of its arguments to be maps.`,
+		`(deep-merge [1] [2])`:                           `error: Deep merge function expects all of its arguments to be maps.`,
+	})
+}
+
+func TestDeepMergeWith(t *testing.T) {
+	testExpressions(t, map[string]string{
+		`(deep-merge-with "replace" {"a" [1 2]} {"a" [3]})`:                                                                          `{"a" [3]}`,
+		`(deep-merge-with :append {"a" [1 2]} {"a" [3]})`:                                                                            `{"a" [1 2 3]}`,
+		`(deep-merge-with "append" {"a" [1]} {"a" [2]} {"a" [3]})`:                                                                   `{"a" [1 2 3]}`,
+		`(deep-merge-with :merge-by-key:name {"s" [{"name" "a" "port" 1} {"name" "b"}]} {"s" [{"name" "a" "port" 2} {"name" "c"}]})`: `{"s" [{"name" "a" "port" 2} {"name" "b"} {"name" "c"}]}`,
+		`(deep-merge-with "append" {"a" 1})`:                                                                                         `error: Not enough arguments passed to deep-merge-with`,
+		`(deep-merge-with "prepend" {"a" 1} {"a" 2})`:                                                                                `error: Unknown merge strategy prepend. Expected replace, append or merge-by-key:key.`,
+		`(deep-merge-with :prepend {"a" 1} {"a" 2})`:                                                                                 `error: Unknown merge strategy prepend. Expected replace, append or merge-by-key:key.`,
+		`(deep-merge-with :merge-by-key {"a" 1} {"a" 2})`:                                                                            `error: Only the merge-by-key strategy takes a key, and it requires one, as in merge-by-key:name.`,
+		`(deep-merge-with "append:name" {"a" 1} {"a" 2})`:                                                                            `error: Only the merge-by-key strategy takes a key, and it requires one, as in merge-by-key:name.`,
+		`(deep-merge-with 1 {"a" 1} {"a" 2})`:                                                                                        `error: Deep merge with function expects its strategy to be a string or keyword.`,
+		`(deep-merge-with {"a" 1} {"a" 2} {"a" 3})`:                                                                                  `error: Deep merge with function expects its strategy to be a string or keyword.`,
+		`(deep-merge-with "append" {"a" 1} [2])`:                                                                                     `error: Deep merge with function expects all of its arguments to be maps.`,
+	})
+}
+
+func TestMergeArgumentCounts(t *testing.T) {
+	// The interpreter rejects calls with fewer arguments than a builtin names, so the checks the builtins make
+	// themselves are tested by calling them directly
+	tests := []struct {
+		Merge func(...interface{}) (uni.Value, error)
+		Args  []interface{}
+		Error string
+	}{
+		{SLIB_Merge, []interface{}{}, "Merge function expects at least one map argument."},
+		{SLIB_DeepMerge, []interface{}{}, "Deep merge function expects at least one map argument."},
+		{SLIB_DeepMergeWith, []interface{}{}, "Deep merge with function expects a strategy and at least one map."},
+		{SLIB_DeepMergeWith, []interface{}{"append"}, "Deep merge with function expects a strategy and at least one map."},
+	}
+	for _, test := range tests {
+		_, err := test.Merge(test.Args...)
+		if err == nil || err.Error() != test.Error {
+			t.Errorf("Expected the error %q merging %v. Got %v\n", test.Error, test.Args, err)
+		}
+	}
+	merged, err := SLIB_Merge(map[string]interface{}{"a": int64(1)})
+	if err != nil || !reflect.DeepEqual(uni.Unwrap(merged), map[string]interface{}{"a": int64(1)}) {
+		t.Errorf("Expected merging a single map to produce that map. Got %v, %v\n", uni.Unwrap(merged), err)
+	}
+}
@@ -7,12 +7,14 @@ import (
 var ConstantNames = []string{
 	"true",
 	"false",
+	"nil",
 	"pi",
 }
 
-var StandardLibrary uni.Environment = uni.Environment{
+var StandardLibrary = map[string]uni.Value{
 	"true":     uni.NewBoolean(true),
 	"false":    uni.NewBoolean(false),
+	"nil":      uni.NewNil(),
 	"pi":       uni.NewFloat(3.141592653589793),
 	"*":        uni.NewCallableFunction("*", []string{"a", "b"}, SLIB_Multiply),
 	"/":        uni.NewCallableFunction("/", []string{"a", "b"}, SLIB_Divide),
@@ -29,6 +31,7 @@ var StandardLibrary uni.Environment = uni.Environment{
 	"at":       uni.NewCallableFunction("at", []string{"_str_", "_index_"}, SLIB_AtIndex),
 	"not":      uni.NewCallableFunction("not", []string{"value"}, SLIB_Negate),
 	"zero?":    uni.NewCallableFunction("zero?", []string{"n"}, SLIB_IsZero),
+	"nil?":     uni.NewCallableFunction("nil?", []string{"value"}, SLIB_IsNil),
 	"and":      uni.NewCallableFunction("and", []string{"b1", "b2"}, SLIB_And),
 	"or":       uni.NewCallableFunction("or", []string{"b1", "b2"}, SLIB_Or),
 	"=":        uni.NewCallableFunction("=", []string{"a", "b"}, SLIB_Equal),
@@ -48,4 +51,15 @@ var StandardLibrary uni.Environment = uni.Environment{
 	"print":    uni.NewCallableFunction("print", []string{"msg"}, SLIB_Print),
 	"env":      uni.NewCallableFunction("env", []string{"_envvar_"}, SLIB_Environment),
 	"ignored":  uni.NewCallableFunction("ignored", []string{"_value_"}, SLIB_Ignore),
+	"error":    uni.NewCallableFunction("error", []string{"_message_"}, SLIB_Error),
+
+	"merge":      uni.NewCallableFunction("merge", []string{"_map1_", "_map2_"}, SLIB_Merge),
+	"deep-merge": uni.NewCallableFunction("deep-merge", []string{"_map1_", "_map2_"}, SLIB_DeepMerge),
+	"deep-merge-with": uni.NewCallableFunction("deep-merge-with", []string{"_strategy_", "_map1_", "_map2_"},
+		SLIB_DeepMergeWith),
+
+	"get-in":    uni.NewCallableFunction("get-in", []string{"_map_", "_path_"}, SLIB_GetIn),
+	"assoc-in":  uni.NewCallableFunction("assoc-in", []string{"_map_", "_path_", "_value_"}, SLIB_AssocIn),
+	"update-in": uni.NewCallableFunction("update-in", []string{"_map_", "_path_", "_function_"}, SLIB_UpdateIn),
+	"dissoc-in": uni.NewCallableFunction("dissoc-in", []string{"_map_", "_path_"}, SLIB_DissocIn),
 }
@@ -0,0 +1,51 @@
+package stdlib
+
+import (
+	uni "../interpreter"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+/**
+ * Evaluate an expression with the standard library available, producing the value it evaluates to.
+ */
+func evaluate(expression string) (uni.Value, error) {
+	env := uni.NewEnvironment(nil)
+	for key, value := range StandardLibrary {
+		env.Define(key, value)
+	}
+	if err := uni.EvaluateProgram("(define (result "+expression+"))", env); err != nil {
+		return uni.Value{}, err
+	}
+	result, _ := env.Lookup("result")
+	return result, nil
+}
+
+/**
+ * Check that expressions evaluate to the values given as Fig source code, or fail with an error containing the
+ * text following "error: ".
+ */
+func testExpressions(t *testing.T, tests map[string]string) {
+	for expression, expected := range tests {
+		value, err := evaluate(expression)
+		if strings.HasPrefix(expected, "error: ") {
+			message := strings.TrimPrefix(expected, "error: ")
+			if err == nil || !strings.Contains(err.Error(), message) {
+				t.Errorf("Expected %s to produce the error %q. Got %v, %v\n", expression, message, uni.Unwrap(value), err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Expected %s to evaluate to %s. Got the error %s\n", expression, expected, err.Error())
+			continue
+		}
+		expectedValue, err := evaluate(expected)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if !reflect.DeepEqual(uni.Unwrap(value), uni.Unwrap(expectedValue)) {
+			t.Errorf("Expected %s to evaluate to %s. Got %v\n", expression, expected, uni.Unwrap(value))
+		}
+	}
+}
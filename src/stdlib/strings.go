@@ -5,15 +5,34 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"unicode/utf8"
 )
 
+/**
+ * Check that an argument to a string function is a string.  Quoted names are not strings, so they are reported
+ * rather than being treated as their text.
+ */
+func stringArgument(name string, argument interface{}) (string, error) {
+	str, isString := argument.(string)
+	if !isString {
+		wrapped, _ := uni.Wrap(argument)
+		errMsg := fmt.Sprintf("%s function expects string arguments. Got %s.", name, kindOf(wrapped))
+		return "", errors.New(errMsg)
+	}
+	return str, nil
+}
+
 func SLIB_Concatenate(arguments ...interface{}) (uni.Value, error) {
 	if len(arguments) < 2 {
 		return uni.Value{}, errors.New("Concatenate function expects two or more arguments.")
 	}
-	result := arguments[0].(string)
-	for i := 1; i < len(arguments); i++ {
-		result += arguments[i].(string)
+	result := ""
+	for _, argument := range arguments {
+		str, err := stringArgument("Concatenate", argument)
+		if err != nil {
+			return uni.Value{}, err
+		}
+		result += str
 	}
 	return uni.NewString(result), nil
 }
@@ -22,16 +41,27 @@ func SLIB_Substring(arguments ...interface{}) (uni.Value, error) {
 	if len(arguments) != 3 {
 		return uni.Value{}, errors.New("Susbtring function expects three arguments.")
 	}
-	str := arguments[0].(string)
-	start := arguments[1].(int64)
-	end := arguments[2].(int64)
+	first, err := stringArgument("Substring", arguments[0])
+	if err != nil {
+		return uni.Value{}, err
+	}
+	start, isStartInt := arguments[1].(int64)
+	end, isEndInt := arguments[2].(int64)
+	if !isStartInt || !isEndInt {
+		return uni.Value{}, errors.New("Substring function expects integer start and end indices.")
+	}
+	// Indices count characters rather than bytes
+	str := []rune(first)
 	if start < 0 {
 		return uni.Value{}, errors.New("Cannot start a substring at a negative index.")
 	}
 	if end > int64(len(str)) {
 		return uni.Value{}, errors.New("Cannot end a substring past the end of the string's length.")
 	}
-	result := str[start:end]
+	if end < start {
+		return uni.Value{}, errors.New("Cannot end a substring before its start.")
+	}
+	result := string(str[start:end])
 	return uni.NewString(result), nil
 }
 
@@ -39,25 +69,53 @@ func SLIB_Index(arguments ...interface{}) (uni.Value, error) {
 	if len(arguments) != 2 {
 		return uni.Value{}, errors.New("Index function expects two arguments.")
 	}
-	first := arguments[0].(string)
-	second := arguments[1].(string)
+	first, err := stringArgument("Index", arguments[0])
+	if err != nil {
+		return uni.Value{}, err
+	}
+	second, err := stringArgument("Index", arguments[1])
+	if err != nil {
+		return uni.Value{}, err
+	}
 	index := strings.Index(first, second)
+	if index > 0 {
+		index = utf8.RuneCountInString(first[:index])
+	}
 	return uni.NewInteger(int64(index)), nil
 }
 
 func SLIB_Length(arguments ...interface{}) (uni.Value, error) {
-	length := len(arguments[0].(string))
+	if len(arguments) != 1 {
+		return uni.Value{}, errors.New("Length function expects exactly one argument.")
+	}
+	str, err := stringArgument("Length", arguments[0])
+	if err != nil {
+		return uni.Value{}, err
+	}
+	length := utf8.RuneCountInString(str)
 	return uni.NewInteger(int64(length)), nil
 }
 
 func SLIB_Upcase(arguments ...interface{}) (uni.Value, error) {
-	str := arguments[0].(string)
+	if len(arguments) != 1 {
+		return uni.Value{}, errors.New("Upcase function expects exactly one argument.")
+	}
+	str, err := stringArgument("Upcase", arguments[0])
+	if err != nil {
+		return uni.Value{}, err
+	}
 	str = strings.ToUpper(str)
 	return uni.NewString(str), nil
 }
 
 func SLIB_Downcase(arguments ...interface{}) (uni.Value, error) {
-	str := arguments[0].(string)
+	if len(arguments) != 1 {
+		return uni.Value{}, errors.New("Downcase function expects exactly one argument.")
+	}
+	str, err := stringArgument("Downcase", arguments[0])
+	if err != nil {
+		return uni.Value{}, err
+	}
 	str = strings.ToLower(str)
 	return uni.NewString(str), nil
 }
@@ -99,10 +157,10 @@ func SLIB_AtIndex(arguments ...interface{}) (uni.Value, error) {
 	default:
 		return uni.Value{}, errors.New("At function expects second argument to be an integer.")
 	}
-	str := arguments[0].(string)
+	str := []rune(arguments[0].(string))
 	index := arguments[1].(int64)
 	if index < int64(0) || index >= int64(len(str)) {
-		errMsg := fmt.Sprintf("String index out of range. Cannot get index %d of \"%s\"", index, str)
+		errMsg := fmt.Sprintf("String index out of range. Cannot get index %d of \"%s\"", index, string(str))
 		return uni.Value{}, errors.New(errMsg)
 	}
 	return uni.NewString(string(str[index])), nil
@@ -0,0 +1,31 @@
+package stdlib
+
+import (
+	"testing"
+)
+
+func TestStringFunctions(t *testing.T) {
+	testExpressions(t, map[string]string{
+		`(concat "a" "b" "c")`:   `"abc"`,
+		`(length "héllo")`:       `5`,
+		`(upcase "abc")`:         `"ABC"`,
+		`(downcase "ABC")`:       `"abc"`,
+		`(substr "hello" 1 3)`:   `"el"`,
+		`(index "hello" "l")`:    `2`,
+		`(concat "a" 1)`:         "error: Concatenate function expects string arguments. Got an integer.",
+		`(substr "hello" "1" 3)`: "error: Substring function expects integer start and end indices.",
+	})
+}
+
+func TestStringFunctionsWithNames(t *testing.T) {
+	// Quoted names are not strings, so string functions report them rather than treating them as their text
+	testExpressions(t, map[string]string{
+		`(length (quote abc))`:         "error: Length function expects string arguments. Got a name.",
+		`(concat (quote a) "b")`:       "error: Concatenate function expects string arguments. Got a name.",
+		`(upcase (quote abc))`:         "error: Upcase function expects string arguments. Got a name.",
+		`(downcase (quote abc))`:       "error: Downcase function expects string arguments. Got a name.",
+		`(index "abc" (quote b))`:      "error: Index function expects string arguments. Got a name.",
+		`(substr (quote hello) 1 3)`:   "error: Substring function expects string arguments. Got a name.",
+		`(length (first (quote (a))))`: "error: Length function expects string arguments. Got a name.",
+	})
+}
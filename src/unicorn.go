@@ -2,14 +2,15 @@ package main
 
 import (
 	codegen "./codegen"
+	formats "./formats"
 	uni "./interpreter"
 	stdlib "./stdlib"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -17,20 +18,56 @@ const HelpMessage = `Run this program as ./unicorn [format file [format file [..
 Currently the supported format flags are
 	-json - Output program state to a JSON file
 	-yaml - Output program state to a YAML file
+	-toml - Output program state to a TOML file
+	-env  - Output program state to a dotenv file of KEY=value lines
+	-sh   - Output program state to a shell script of export KEY=value lines
+	-ini  - Output program state to an INI file, with a section for each top-level map
+	-properties - Output program state to a Java .properties file of dotted keys
+	-hcl  - Output program state to an HCL file, writing maps as blocks
+	-tfvars - Output program state to a Terraform .tfvars file of attributes
+	-xml  - Output program state to an XML file, inside of a <config> element
 	-go   - Output a Go source code file containing a Configuration struct and parser functions
 
+The name of the XML root element can be changed with
+	-xml-root - The name of the element containing the program state in XML output
+
+Files imported by Fig programs are searched for relative to the importing file first. Additional
+directories to search can be provided with
+	-path - A directory to search for imported files in. May be provided more than once
+
+To safely run untrusted Fig programs, the resources they may use can be limited with
+	-max-steps - The maximum number of expressions a program may evaluate
+	-max-depth - The maximum depth of nested function calls
+	-max-size  - The maximum number of elements in any one list or map
+
 At least one Fig program must be provided.
 
 When more than one Fig program is provided, each will be run one after the other, and the
 environment (global scope) produced by each will be made the environment of successive programs.
 Therefore, one can run multiple Fig programs to effectively combine their outputs into a single
 configuration.
+
+By default, a later program that defines a name replaces the value an earlier program gave it. To layer
+programs instead, so that the maps defined by later programs are deep-merged into those of earlier ones, use
+	-merge - The strategy for combining lists found in both programs' maps. One of
+	         replace        - Use the later program's list
+	         append         - Append the later program's list to the earlier one
+	         merge-by-key:k - Merge maps in the lists that have the same value for the key k
 `
 
 var SupportedFormatHandlers = map[string]func(map[string]interface{}, string) error{
 	"json": WriteJSON,
 	"yaml": WriteYAML,
+	"toml": formats.WriteTOML,
+	"env":  formats.WriteDotenv,
+	"sh":   formats.WriteShell,
 	"go":   codegen.GenerateConfigCodeFile,
+
+	"ini":        formats.WriteINI,
+	"properties": formats.WriteProperties,
+	"hcl":        formats.WriteHCL,
+	"tfvars":     formats.WriteTFVars,
+	"xml":        formats.WriteXML,
 }
 
 func WriteJSON(env map[string]interface{}, fileName string) error {
@@ -61,10 +98,10 @@ func WriteYAML(env map[string]interface{}, fileName string) error {
 	return writeErr
 }
 
-func WriteOutputFiles(formats map[string]string, data uni.Environment) error {
+func WriteOutputFiles(formats map[string]string, data *uni.Environment) error {
 	// Strip out values that we can't encode, like functions, as well as constants defined in Unicorn.
 	toWrite := make(map[string]interface{})
-	for k, v := range data {
+	for k, v := range data.Bindings {
 		if v.Ignored {
 			continue
 		}
@@ -93,35 +130,20 @@ func WriteOutputFiles(formats map[string]string, data uni.Environment) error {
 	return nil
 }
 
-func Interpret(program string, env uni.Environment) (uni.Environment, error) {
+func Interpret(program string, env *uni.Environment) (*uni.Environment, error) {
 	// Copy the standard library into the local scope so we don't corrupt the former
 	for key, value := range stdlib.StandardLibrary {
-		env[key] = value
+		env.Define(key, value)
 	}
-	lexed, length := uni.Lex(program, 0)
-	if length != len(program) {
-		return env, errors.New("Could not lex to the end of your program. Check that it is properly formatted.")
-	}
-	parseErr, parsedForms := uni.Parse(lexed)
-	if parseErr != nil {
-		return env, parseErr
-	}
-	var err error = nil
-	//var value uni.Value
-	//value := uni.Value{}
-	for _, form := range parsedForms {
-		err, _, env = uni.Evaluate(form, env)
-		//err, value, env = uni.Evaluate(form, env)
-		if err != nil {
-			return uni.Environment{}, err
-		}
+	if err := env.Loader.EvaluateEntry(program, env); err != nil {
+		return uni.NewEnvironment(nil), err
 	}
 	return env, nil
 }
 
 func main() {
 	if len(os.Args) < 2 {
-		fmt.Println(HelpMessage)
+		fmt.Print(HelpMessage)
 		return
 	}
 	// Maps are supported output file formats and values are names of files to write to if any.
@@ -129,6 +151,16 @@ func main() {
 	for format, _ := range SupportedFormatHandlers {
 		outputFormats[format] = ""
 	}
+	// Directories to search for imported files in, besides the directory of the importing file
+	searchPaths := []string{}
+	limits := &uni.Limits{}
+	// How to combine the definitions of successive programs, if they are layered rather than replaced
+	var layering *uni.MergeStrategy
+	limitFlags := map[string]*int{
+		"maxsteps": &limits.MaxSteps,
+		"maxdepth": &limits.MaxDepth,
+		"maxsize":  &limits.MaxSize,
+	}
 	// Parse arguments in any form such as "--json output.json -YAML data.yaml myprogram.fig"
 	i := 1
 	for ; i < len(os.Args)-1; i++ {
@@ -140,13 +172,37 @@ func main() {
 		if isSupported {
 			outputFormats[format] = os.Args[i+1]
 			i++
+		} else if format == "path" {
+			searchPaths = append(searchPaths, os.Args[i+1])
+			i++
+		} else if format == "xmlroot" {
+			SupportedFormatHandlers["xml"] = formats.XMLWriter(os.Args[i+1])
+			i++
+		} else if format == "merge" {
+			strategy, err := uni.ParseMergeStrategy(os.Args[i+1])
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			layering = &strategy
+			i++
+		} else if limit, isLimit := limitFlags[format]; isLimit {
+			max, err := strconv.Atoi(os.Args[i+1])
+			if err != nil || max < 0 {
+				fmt.Println("Limits must be non-negative integers. Got " + os.Args[i+1] + " for " + os.Args[i])
+				return
+			}
+			*limit = max
+			i++
 		}
 	}
 	// Treat all arguments after the flags as source files
-	env := uni.Environment{}
+	env := uni.NewEnvironment(nil)
+	loader := uni.NewLoader(stdlib.StandardLibrary, searchPaths...)
+	loader.Limits = limits
 	if i == len(os.Args) {
 		fmt.Println("No input program file provided.")
-		fmt.Println(HelpMessage)
+		fmt.Print(HelpMessage)
 		return
 	}
 	for ; i < len(os.Args); i++ {
@@ -165,7 +221,18 @@ func main() {
 			return
 		}
 		program := string(programBytes)
-		env, err = Interpret(program, env)
+		env.File = programFile
+		env.Loader = loader
+		env.Limits = limits
+		if layering == nil {
+			env, err = Interpret(program, env)
+		} else {
+			// Run the program in a layer on top of the earlier programs, so it can still refer to their
+			// definitions, and then merge what it defines into them
+			var layer *uni.Environment
+			layer, err = Interpret(program, uni.NewEnvironment(env))
+			env.MergeLayer(layer, *layering)
+		}
 		if err != nil {
 			fmt.Println("ERROR\n  ", err.Error())
 		}